@@ -1,20 +1,26 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
 
-	"github.com/upfluence/sensu-go/sensu/transport/rabbitmq"
 	"github.com/upfluence/sensu-client-go/sensu"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(sensu.VersionString())
+		return
+	}
+
 	cfg, err := sensu.NewConfigFromFlagSet(sensu.ExtractFlags())
 
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	t, err := rabbitmq.NewRabbitMQTransport(cfg.RabbitMQURI())
+	t, err := sensu.NewTransport(cfg)
 
 	if err != nil {
 		log.Fatal(err.Error())
@@ -0,0 +1,163 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/upfluence/goutils/log"
+)
+
+const (
+	ioPrioClassShift = 13
+	ioPrioWhoPgrp    = 2
+)
+
+// validatePlatform checks the cgroup exists with the controllers required
+// by the limits
+func (p Process) validatePlatform() error {
+	if p.Cgroup == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(p.Cgroup) {
+		return fmt.Errorf("The cgroup has to be an absolute path: %s", p.Cgroup)
+	}
+
+	controllers, err := ioutil.ReadFile(filepath.Join(p.Cgroup, "cgroup.controllers"))
+
+	if err != nil {
+		return fmt.Errorf("Not a cgroup v2 directory: %s", p.Cgroup)
+	}
+
+	if p.MemoryMax != 0 && !hasField(string(controllers), "memory") {
+		return fmt.Errorf("The memory controller isn't available in the cgroup %s", p.Cgroup)
+	}
+
+	return nil
+}
+
+func setIOPriority(pgid, class, priority int) error {
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOPRIO_SET,
+		ioPrioWhoPgrp,
+		uintptr(pgid),
+		uintptr(class<<ioPrioClassShift|priority),
+	); errno != 0 && errno != syscall.ESRCH {
+		return errno
+	}
+
+	return nil
+}
+
+// cgroup is the child group of the configured cgroup a check runs in, it is
+// created for every execution so its memory events are the check ones
+type cgroup struct {
+	path string
+}
+
+func newCgroup(root, name string, memoryMax uint64) (*cgroup, error) {
+	if memoryMax > 0 {
+		if err := writeCgroupFile(root, "cgroup.subtree_control", "+memory"); err != nil {
+			return nil, err
+		}
+	}
+
+	path, err := ioutil.TempDir(root, cgroupName(name)+"-")
+
+	if err != nil {
+		return nil, err
+	}
+
+	g := &cgroup{path: path}
+
+	if memoryMax > 0 {
+		if err := writeCgroupFile(path, "memory.max", strconv.FormatUint(memoryMax, 10)); err != nil {
+			g.remove()
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// add moves the process into the group, the check is held until then so
+// its children start in the group too
+func (g *cgroup) add(pid int) error {
+	return writeCgroupFile(g.path, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// oomKilled reports whether a process of the group was killed by the
+// memory limit
+func (g *cgroup) oomKilled() bool {
+	f, err := os.Open(filepath.Join(g.path, "memory.events"))
+
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, _ := strconv.ParseUint(fields[1], 10, 64)
+			return n > 0
+		}
+	}
+
+	return false
+}
+
+// remove kills the processes left in the group, like the daemonized ones,
+// and removes it
+func (g *cgroup) remove() {
+	writeCgroupFile(g.path, "cgroup.kill", "1")
+
+	if err := os.Remove(g.path); err != nil {
+		log.Warningf("Can't remove the cgroup %s: %s", g.path, err.Error())
+	}
+}
+
+// writeCgroupFile writes an interface file of the group, which has to exist
+func writeCgroupFile(dir, name, value string) error {
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// cgroupName keeps the characters of the check name allowed by sensu
+func cgroupName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '-', r == '.':
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if name == "" || name == "." || name == ".." {
+		return "check"
+	}
+
+	return name
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+// +build !linux
+
+package check
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNotSupported = errors.New("Not supported on this platform")
+
+// validatePlatform rejects the cgroup and the I/O priority, which are
+// specific to linux
+func (p Process) validatePlatform() error {
+	if p.Cgroup != "" {
+		return fmt.Errorf("The cgroup isn't supported on this platform")
+	}
+
+	if p.IOClass != "" {
+		return fmt.Errorf("The io_class isn't supported on this platform")
+	}
+
+	return nil
+}
+
+func setIOPriority(pgid, class, priority int) error {
+	return errNotSupported
+}
+
+type cgroup struct{}
+
+func newCgroup(root, name string, memoryMax uint64) (*cgroup, error) {
+	return nil, errNotSupported
+}
+
+func (g *cgroup) add(pid int) error {
+	return errNotSupported
+}
+
+func (g *cgroup) oomKilled() bool {
+	return false
+}
+
+func (g *cgroup) remove() {}
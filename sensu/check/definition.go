@@ -0,0 +1,96 @@
+package check
+
+import (
+	"encoding/json"
+
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+// Definition extends the sensu-go check definition with the attributes
+// supported by this client
+type Definition struct {
+	*stdCheck.Check
+	Process
+	Timeout int64 `json:"timeout,omitempty"`
+	// attributes holds the raw attributes of the decoded definitions, so the
+	// ones set to their zero value are known
+	attributes map[string]json.RawMessage
+}
+
+func (d *Definition) UnmarshalJSON(b []byte) error {
+	type plain Definition
+
+	var p plain
+
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, &p.attributes); err != nil {
+		return err
+	}
+
+	*d = Definition(p)
+
+	return nil
+}
+
+// Attributes returns the attributes set in the definition, including the
+// ones explicitly set to their zero value when it was decoded from JSON
+func (d *Definition) Attributes() (map[string]json.RawMessage, error) {
+	attributes := make(map[string]json.RawMessage)
+
+	buf, err := json.Marshal(d)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &attributes); err != nil {
+		return nil, err
+	}
+
+	// The attributes omitted from the output were set to their zero value
+	for k, v := range d.attributes {
+		if _, ok := attributes[k]; !ok {
+			attributes[k] = v
+		}
+	}
+
+	return attributes, nil
+}
+
+type Request struct {
+	*Definition
+	Issued int64 `json:"issued,omitempty"`
+}
+
+func (r *Request) UnmarshalJSON(b []byte) error {
+	var (
+		definition Definition
+		p          struct {
+			Issued int64 `json:"issued,omitempty"`
+		}
+	)
+
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+
+	if err := definition.UnmarshalJSON(b); err != nil {
+		return err
+	}
+
+	// The attributes of the requests are never merged into anything
+	definition.attributes = nil
+
+	*r = Request{Definition: &definition, Issued: p.Issued}
+
+	return nil
+}
+
+// CheckRequest returns the sensu-go version of the request, as embedded in
+// the check outputs
+func (r *Request) CheckRequest() *stdCheck.CheckRequest {
+	return &stdCheck.CheckRequest{Check: r.Check, Issued: r.Issued}
+}
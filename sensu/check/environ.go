@@ -0,0 +1,131 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// environ returns the environment of the check, nil if it inherits the
+// client one
+func (p Process) environ() []string {
+	if p.Env == nil && !p.CleanEnv && len(p.PluginPaths) == 0 && p.User == "" {
+		return nil
+	}
+
+	vars := make(map[string]string)
+
+	for _, kv := range os.Environ() {
+		if v := strings.SplitN(kv, "=", 2); len(v) == 2 && (!p.CleanEnv || v[0] == "PATH") {
+			vars[v[0]] = v[1]
+		}
+	}
+
+	// The checks run as another user get its home and name, the unknown
+	// users are checked by Credential
+	if p.User != "" {
+		if u, err := lookupUser(p.User); err == nil {
+			setOrDelete(vars, "HOME", u.HomeDir)
+			setOrDelete(vars, "USER", u.Username)
+		}
+	}
+
+	for k, v := range p.Env {
+		if v == nil {
+			delete(vars, k)
+		} else {
+			vars[k] = *v
+		}
+	}
+
+	if len(p.PluginPaths) > 0 {
+		path := strings.Join(p.PluginPaths, string(os.PathListSeparator))
+
+		if v := vars["PATH"]; v != "" {
+			path += string(os.PathListSeparator) + v
+		}
+
+		vars["PATH"] = path
+	}
+
+	env := make([]string, 0, len(vars))
+
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+
+	sort.Strings(env)
+
+	return env
+}
+
+func setOrDelete(vars map[string]string, k, v string) {
+	if v == "" {
+		delete(vars, k)
+	} else {
+		vars[k] = v
+	}
+}
+
+// LookPath searches the executable in the PATH of the check, like the shell
+// running it does
+func (p Process) LookPath(name string) (string, error) {
+	env := p.environ()
+
+	if env == nil || strings.Contains(name, "/") {
+		return exec.LookPath(name)
+	}
+
+	var path string
+
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "PATH=") {
+			path = strings.TrimPrefix(kv, "PATH=")
+		}
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		// The relative directories are skipped like exec.LookPath does
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+
+		file := filepath.Join(dir, name)
+
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() &&
+			info.Mode()&0111 != 0 {
+			return file, nil
+		}
+	}
+
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+func (p Process) validateEnviron() error {
+	if p.Cwd != "" {
+		if !filepath.IsAbs(p.Cwd) {
+			return fmt.Errorf("The cwd has to be an absolute path: %s", p.Cwd)
+		}
+
+		if info, err := os.Stat(p.Cwd); err != nil || !info.IsDir() {
+			return fmt.Errorf("The cwd isn't a directory: %s", p.Cwd)
+		}
+	}
+
+	for _, dir := range p.PluginPaths {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("The plugin paths have to be absolute: %s", dir)
+		}
+	}
+
+	for k := range p.Env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return fmt.Errorf("Invalid env variable name: %q", k)
+		}
+	}
+
+	return nil
+}
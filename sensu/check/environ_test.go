@@ -0,0 +1,206 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestEnviron(t *testing.T) {
+	os.Setenv("SENSU_TEST_SECRET", "1234")
+	defer os.Unsetenv("SENSU_TEST_SECRET")
+
+	path := os.Getenv("PATH")
+
+	for _, tCase := range []struct {
+		process  Process
+		contains []string
+		excludes []string
+	}{
+		{
+			Process{Env: map[string]*string{"FOO": stringPtr("bar"), "SENSU_TEST_SECRET": nil}},
+			[]string{"FOO=bar", "PATH=" + path},
+			[]string{"SENSU_TEST_SECRET=1234"},
+		},
+		{
+			Process{CleanEnv: true, Env: map[string]*string{"FOO": stringPtr("bar")}},
+			[]string{"FOO=bar", "PATH=" + path},
+			[]string{"SENSU_TEST_SECRET=1234"},
+		},
+		{
+			Process{PluginPaths: []string{"/etc/sensu/plugins", "/opt/plugins"}},
+			[]string{"PATH=/etc/sensu/plugins:/opt/plugins:" + path, "SENSU_TEST_SECRET=1234"},
+			nil,
+		},
+		{
+			Process{CleanEnv: true, Env: map[string]*string{"PATH": nil}, PluginPaths: []string{"/opt/plugins"}},
+			[]string{"PATH=/opt/plugins"},
+			[]string{"SENSU_TEST_SECRET=1234"},
+		},
+	} {
+		env := make(map[string]bool)
+
+		for _, kv := range tCase.process.environ() {
+			env[kv] = true
+		}
+
+		for _, kv := range tCase.contains {
+			if !env[kv] {
+				t.Errorf("Expected the environment of %+v to contain %s!", tCase.process, kv)
+			}
+		}
+
+		for _, kv := range tCase.excludes {
+			if env[kv] {
+				t.Errorf("Expected the environment of %+v not to contain %s!", tCase.process, kv)
+			}
+		}
+	}
+
+	if env := (Process{}).environ(); env != nil {
+		t.Errorf("Expected the client environment to be inherited but got %v instead!", env)
+	}
+}
+
+func TestEnvironAsUser(t *testing.T) {
+	os.Setenv("SENSU_TEST_SECRET", "1234")
+	defer os.Unsetenv("SENSU_TEST_SECRET")
+
+	for _, tCase := range []struct {
+		process  Process
+		contains []string
+		excludes []string
+	}{
+		{
+			Process{User: "nobody"},
+			[]string{"HOME=/nonexistent", "USER=nobody", "SENSU_TEST_SECRET=1234"},
+			nil,
+		},
+		{
+			Process{User: "nobody", Env: map[string]*string{"HOME": stringPtr("/tmp")}},
+			[]string{"HOME=/tmp", "USER=nobody"},
+			nil,
+		},
+		{
+			Process{User: "424242"},
+			nil,
+			[]string{"HOME=" + os.Getenv("HOME")},
+		},
+	} {
+		env := make(map[string]bool)
+
+		for _, kv := range tCase.process.environ() {
+			env[kv] = true
+		}
+
+		for _, kv := range tCase.contains {
+			if !env[kv] {
+				t.Errorf("Expected the environment of %+v to contain %s!", tCase.process, kv)
+			}
+		}
+
+		for _, kv := range tCase.excludes {
+			if env[kv] {
+				t.Errorf("Expected the environment of %+v not to contain %s!", tCase.process, kv)
+			}
+		}
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	p := Process{Env: map[string]*string{"FOO": stringPtr("foo"), "BAR": stringPtr("bar")}}.Merge(
+		Process{Env: map[string]*string{"FOO": nil, "BAZ": stringPtr("baz")}},
+	)
+
+	expected := map[string]*string{"FOO": nil, "BAR": stringPtr("bar"), "BAZ": stringPtr("baz")}
+
+	if !reflect.DeepEqual(p.Env, expected) {
+		t.Errorf("Expected %v but got %v instead!", expected, p.Env)
+	}
+}
+
+func newPluginDir(t *testing.T) string {
+	dir := newTempDir(t)
+
+	if err := ioutil.WriteFile(
+		filepath.Join(dir, "check-env"),
+		[]byte("#!/bin/sh\necho \"$FOO $(pwd)\"\n"),
+		0755,
+	); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	return dir
+}
+
+func TestLookPath(t *testing.T) {
+	dir := newPluginDir(t)
+	defer os.RemoveAll(dir)
+
+	path, err := Process{PluginPaths: []string{dir}}.LookPath("check-env")
+
+	if err != nil || path != filepath.Join(dir, "check-env") {
+		t.Errorf("Expected %s but got %s, %v instead!", filepath.Join(dir, "check-env"), path, err)
+	}
+
+	if _, err := (Process{}).LookPath("check-env"); err == nil {
+		t.Error("Expected the plugin not to be found in the client PATH!")
+	}
+
+	if _, err := (Process{PluginPaths: []string{"plugins"}}).LookPath("check-env"); err == nil {
+		t.Error("Expected the relative directories to be skipped!")
+	}
+}
+
+func TestExternalCheckEnviron(t *testing.T) {
+	dir := newPluginDir(t)
+	defer os.RemoveAll(dir)
+	cwd := newTempDir(t)
+	defer os.RemoveAll(cwd)
+
+	for _, direct := range []bool{false, true} {
+		r := (&ExternalCheck{
+			Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "check-env"}},
+			Direct:  direct,
+			Process: Process{
+				Env:         map[string]*string{"FOO": stringPtr("bar")},
+				Cwd:         cwd,
+				PluginPaths: []string{dir},
+				CPUTime:     5,
+			},
+		}).Execute()
+
+		if r.Status != stdCheck.Success || r.Output != "bar "+cwd+"\n" {
+			t.Errorf("Expected \"bar %s\" but got %d \"%s\" instead!", cwd, r.Status, r.Output)
+		}
+	}
+}
+
+func TestValidateEnviron(t *testing.T) {
+	for _, tCase := range []struct {
+		process Process
+		err     string
+	}{
+		{Process{Cwd: "/", PluginPaths: []string{"/etc/sensu/plugins"}}, ""},
+		{Process{Cwd: "tmp"}, "The cwd has to be an absolute path: tmp"},
+		{Process{Cwd: "/nonexistent"}, "The cwd isn't a directory: /nonexistent"},
+		{Process{PluginPaths: []string{"plugins"}}, "The plugin paths have to be absolute: plugins"},
+		{Process{Env: map[string]*string{"FOO=BAR": nil}}, "Invalid env variable name: \"FOO=BAR\""},
+	} {
+		err := tCase.process.Validate()
+
+		if tCase.err == "" && err != nil {
+			t.Errorf("Expected a nil error for %+v but got \"%s\" instead!", tCase.process, err)
+		} else if tCase.err != "" && (err == nil || err.Error() != tCase.err) {
+			t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+		}
+	}
+}
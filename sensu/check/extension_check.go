@@ -1,8 +1,7 @@
 package check
 
 import (
-	"time"
-
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 )
 
@@ -16,14 +15,18 @@ type ExtensionCheck struct {
 }
 
 func (c *ExtensionCheck) Execute() stdCheck.CheckOutput {
-	t0 := time.Now()
+	return c.ExecuteWithClock(clock.Real)
+}
+
+func (c *ExtensionCheck) ExecuteWithClock(clk clock.Clock) stdCheck.CheckOutput {
+	t0 := clk.Now()
 
 	output := c.Function()
 
 	return stdCheck.CheckOutput{
 		Status:   output.Status,
 		Output:   output.Output,
-		Duration: time.Since(t0).Seconds(),
+		Duration: clk.Now().Sub(t0).Seconds(),
 		Executed: t0.Unix(),
 	}
 }
@@ -2,7 +2,9 @@ package check
 
 import (
 	"testing"
+	"time"
 
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 )
 
@@ -25,3 +27,22 @@ func TestExtension(t *testing.T) {
 		t.Errorf("The duration is not positive: %f", r.Duration)
 	}
 }
+
+func TestExtensionWithClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+
+	r := (&ExtensionCheck{
+		func() ExtensionCheckResult {
+			clk.Advance(1500 * time.Millisecond)
+			return FunctionT()
+		},
+	}).ExecuteWithClock(clk)
+
+	if r.Executed != 1479057736 {
+		t.Errorf("Wrong execution time: %d", r.Executed)
+	}
+
+	if r.Duration != 1.5 {
+		t.Errorf("Expected the duration to be 1.5s but got %f instead!", r.Duration)
+	}
+}
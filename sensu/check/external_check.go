@@ -2,48 +2,240 @@ package check
 
 import (
 	"bytes"
+	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 )
 
+const (
+	timeoutOutput      = "Execution timed out"
+	cpuTimeLimitOutput = "Execution killed: CPU time limit of %ds exceeded"
+	memoryLimitOutput  = "Execution killed: memory limit of %d bytes exceeded"
+)
+
 type ExternalCheck struct {
 	Request *stdCheck.CheckRequest
+	// Kills the command when exceeded, zero means no timeout
+	Timeout time.Duration
+	// Executes the command without shell, its arguments are split on
+	// whitespaces
+	Direct bool
+	// User, groups and resource limits of the command
+	Process Process
 }
 
 func (c *ExternalCheck) Execute() stdCheck.CheckOutput {
-	t0 := time.Now()
-	cmd := exec.Command("/bin/sh", "-c", c.Request.Command)
+	return c.ExecuteWithClock(clock.Real)
+}
+
+func (c *ExternalCheck) ExecuteWithClock(clk clock.Clock) stdCheck.CheckOutput {
+	t0 := clk.Now()
 	var out bytes.Buffer
-	cmd.Stdout = &out
+	var group *cgroup
+
+	cmd, err := c.command()
+
+	if err == nil {
+		cmd.Stdout = &out
+		// Run the command in its own process group so the whole group can be
+		// killed on timeout, not only the shell
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+		group, err = c.start(cmd)
+	}
+
+	if group != nil {
+		defer group.remove()
+	}
 
-	if err := cmd.Start(); err != nil {
+	if err != nil {
 		return stdCheck.CheckOutput{
 			CheckRequest: c.Request,
 			Status:       stdCheck.Error,
 			Output:       err.Error(),
-			Duration:     time.Since(t0).Seconds(),
+			Duration:     clk.Now().Sub(t0).Seconds(),
 			Executed:     t0.Unix(),
 		}
 	}
 
-	status := stdCheck.Success
+	var timeout <-chan time.Time
+
+	if c.Timeout > 0 {
+		timer := clk.NewTimer(c.Timeout)
+		defer timer.Stop()
+
+		timeout = timer.C()
+	}
+
+	done := make(chan error, 1)
+
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		status, output := exitStatus(err), out.String()
+
+		if reason := c.limitExceeded(cmd.ProcessState, group); reason != "" {
+			status, output = stdCheck.Error, reason
+		}
+
+		return stdCheck.CheckOutput{
+			Status:   status,
+			Output:   output,
+			Duration: clk.Now().Sub(t0).Seconds(),
+			Executed: t0.Unix(),
+		}
+	case <-timeout:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+
+		return stdCheck.CheckOutput{
+			Status:   stdCheck.Error,
+			Output:   timeoutOutput,
+			Duration: clk.Now().Sub(t0).Seconds(),
+			Executed: t0.Unix(),
+		}
+	}
+}
+
+// start runs the command as the configured user, in its cgroup and with its
+// priority
+func (c *ExternalCheck) start(cmd *exec.Cmd) (*cgroup, error) {
+	credential, err := c.Process.Credential()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.SysProcAttr.Credential = credential
+
+	var group *cgroup
+
+	if c.Process.Cgroup != "" {
+		group, err = newCgroup(c.Process.Cgroup, c.Request.Name, c.Process.MemoryMax)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't create the cgroup of the check: %s", err.Error())
+		}
+	}
+
+	if !c.Process.isHeld() {
+		return group, cmd.Start()
+	}
+
+	// The shell waits for the cgroup and the priority to be set before
+	// executing the command, until the pipe is closed
+	r, w, err := os.Pipe()
+
+	if err != nil {
+		return group, err
+	}
+
+	defer w.Close()
+
+	cmd.ExtraFiles = []*os.File{r}
+	err = cmd.Start()
+	r.Close()
+
+	if err != nil {
+		return group, err
+	}
+
+	if group != nil {
+		err = group.add(cmd.Process.Pid)
+
+		if err != nil {
+			err = fmt.Errorf("Can't move the check to its cgroup: %s", err.Error())
+		}
+	}
+
+	if err == nil && c.Process.hasPriority() {
+		err = c.Process.setPriority(cmd.Process.Pid)
+	}
+
+	if err != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		cmd.Wait()
+
+		return group, err
+	}
+
+	return group, nil
+}
+
+// limitExceeded returns the output reporting the resource limit which
+// killed the command, if any
+func (c *ExternalCheck) limitExceeded(state *os.ProcessState, group *cgroup) string {
+	if group != nil && c.Process.MemoryMax > 0 && group.oomKilled() {
+		return fmt.Sprintf(memoryLimitOutput, c.Process.MemoryMax)
+	}
+
+	if c.Process.cpuTimeExceeded(state) {
+		return fmt.Sprintf(cpuTimeLimitOutput, c.Process.CPUTime)
+	}
+
+	return ""
+}
+
+func (c *ExternalCheck) command() (*exec.Cmd, error) {
+	var args []string
+
+	if c.Direct {
+		args = strings.Fields(c.Request.Command)
+	} else {
+		args = []string{"/bin/sh", "-c", c.Request.Command}
+	}
+
+	if len(args) == 0 {
+		return exec.Command(""), nil
+	}
+
+	// exec.Command looks the executable up in the client PATH
+	if c.Direct {
+		path, err := c.Process.LookPath(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		args[0] = path
+	}
+
+	// The limits are set by a shell which then executes the command, so
+	// they apply from its start
+	if limits := c.Process.ulimits(); len(limits) > 0 || c.Process.isHeld() {
+		script := strings.Join(append(limits, `exec "$@"`), " && ")
 
-	if err := cmd.Wait(); err != nil {
-		status = stdCheck.Error
-		if exiterr, ok := err.(*exec.ExitError); ok {
-			if statusReturn, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-				status = stdCheck.ExitStatus(statusReturn.ExitStatus())
-			}
+		if c.Process.isHeld() {
+			script = "read _ <&3; exec 3<&-; " + script
 		}
+
+		args = append([]string{"/bin/sh", "-c", script, "sh"}, args...)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = c.Process.environ()
+	cmd.Dir = c.Process.Cwd
+
+	return cmd, nil
+}
+
+func exitStatus(err error) stdCheck.ExitStatus {
+	if err == nil {
+		return stdCheck.Success
 	}
 
-	return stdCheck.CheckOutput{
-		Status:   status,
-		Output:   out.String(),
-		Duration: time.Since(t0).Seconds(),
-		Executed: t0.Unix(),
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		if statusReturn, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+			return stdCheck.ExitStatus(statusReturn.ExitStatus())
+		}
 	}
+
+	return stdCheck.Error
 }
@@ -1,14 +1,17 @@
 package check
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 )
 
 func TestEmptyCommand(t *testing.T) {
 	r := (&ExternalCheck{
-		&stdCheck.CheckRequest{Check: &stdCheck.Check{}},
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{}},
 	}).Execute()
 
 	if r.Status != stdCheck.Success {
@@ -22,7 +25,7 @@ func TestEmptyCommand(t *testing.T) {
 
 func TestCorrectCommand(t *testing.T) {
 	r := (&ExternalCheck{
-		&stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "ls"}},
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "ls"}},
 	}).Execute()
 
 	if r.Status != stdCheck.Success {
@@ -36,7 +39,7 @@ func TestCorrectCommand(t *testing.T) {
 
 func TestOtherExitCodeCommand(t *testing.T) {
 	r := (&ExternalCheck{
-		&stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "lsi /fiz/fux"}},
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "lsi /fiz/fux"}},
 	}).Execute()
 
 	if r.Status != 127 {
@@ -50,7 +53,7 @@ func TestOtherExitCodeCommand(t *testing.T) {
 
 func TestCustomeExitCodeCommand(t *testing.T) {
 	r := (&ExternalCheck{
-		&stdCheck.CheckRequest{
+		Request: &stdCheck.CheckRequest{
 			Check: &stdCheck.Check{Command: `/bin/bash -c "echo 'foo'; exit 42"`},
 		},
 	}).Execute()
@@ -63,3 +66,88 @@ func TestCustomeExitCodeCommand(t *testing.T) {
 		t.Errorf("Wrong output: %v", r.Output)
 	}
 }
+
+func TestTimeoutCommand(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+	outputs := make(chan stdCheck.CheckOutput, 1)
+
+	go func() {
+		outputs <- (&ExternalCheck{
+			Request: &stdCheck.CheckRequest{
+				Check: &stdCheck.Check{Command: "echo foo; sleep 5 | cat"},
+			},
+			Timeout: 10 * time.Second,
+		}).ExecuteWithClock(clk)
+	}()
+
+	for clk.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clk.Advance(10 * time.Second)
+
+	r := <-outputs
+
+	if r.Status != stdCheck.Error {
+		t.Errorf("The status is not error, %d", r.Status)
+	}
+
+	if r.Output != timeoutOutput {
+		t.Errorf("Wrong output: %v", r.Output)
+	}
+
+	if r.Duration != 10.0 {
+		t.Errorf("Expected the duration to be 10s but got %f instead!", r.Duration)
+	}
+
+	if r.Executed != 1479057736 {
+		t.Errorf("Wrong execution time: %d", r.Executed)
+	}
+}
+
+func TestCommandWithinTimeout(t *testing.T) {
+	r := (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "echo foo"}},
+		Timeout: 5 * time.Second,
+	}).Execute()
+
+	if r.Status != stdCheck.Success {
+		t.Errorf("The status is not success, %d", r.Status)
+	}
+
+	if r.Output != "foo\n" {
+		t.Errorf("Wrong output: %v", r.Output)
+	}
+}
+
+func TestDirectCommand(t *testing.T) {
+	r := (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "printf  foo;$HOME"}},
+		Direct:  true,
+	}).Execute()
+
+	if r.Status != stdCheck.Success {
+		t.Errorf("The status is not success, %d", r.Status)
+	}
+
+	if r.Output != "foo;$HOME" {
+		t.Errorf("Wrong output: %v", r.Output)
+	}
+
+	r = (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: " "}},
+		Direct:  true,
+	}).Execute()
+
+	if r.Status != stdCheck.Error {
+		t.Errorf("The status is not error, %d", r.Status)
+	}
+	r = (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "check-nonexistent foo"}},
+		Direct:  true,
+	}).Execute()
+
+	if r.Status != stdCheck.Error || !strings.Contains(r.Output, "check-nonexistent") {
+		t.Errorf("Expected an error naming check-nonexistent but got %d \"%s\" instead!", r.Status, r.Output)
+	}
+}
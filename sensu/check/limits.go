@@ -0,0 +1,143 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var ioClasses = map[string]int{"realtime": 1, "best-effort": 2, "idle": 3}
+
+// Validate reports the inconsistent settings
+func (p Process) Validate() error {
+	if err := p.validateEnviron(); err != nil {
+		return err
+	}
+
+	if p.Nice < -20 || p.Nice > 19 {
+		return fmt.Errorf("Invalid nice value: %d", p.Nice)
+	}
+
+	if _, ok := ioClasses[p.IOClass]; p.IOClass != "" && !ok {
+		return fmt.Errorf("Invalid io_class: %s", p.IOClass)
+	}
+
+	if p.IOPriority != nil {
+		if p.IOClass == "" {
+			return fmt.Errorf("The io_priority requires an io_class")
+		}
+
+		if *p.IOPriority < 0 || *p.IOPriority > 7 {
+			return fmt.Errorf("Invalid io_priority: %d", *p.IOPriority)
+		}
+	}
+
+	if p.Cgroup == "" && p.MemoryMax != 0 {
+		return fmt.Errorf("The memory_max requires a cgroup")
+	}
+
+	return p.validatePlatform()
+}
+
+// ulimits returns the shell commands setting the resource limits, the hard
+// CPU time limit is a second above the soft one so the check gets SIGXCPU
+// before SIGKILL
+func (p Process) ulimits() []string {
+	var limits []string
+
+	if p.CPUTime > 0 {
+		limits = append(
+			limits,
+			fmt.Sprintf("ulimit -S -t %d", p.CPUTime),
+			fmt.Sprintf("ulimit -H -t %d", p.CPUTime+1),
+		)
+	}
+
+	if p.AddressSpace > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", (p.AddressSpace+1023)/1024))
+	}
+
+	if p.OpenFiles > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -n %d", p.OpenFiles))
+	}
+
+	return limits
+}
+
+func (p Process) hasPriority() bool {
+	return p.Nice != 0 || p.IOClass != ""
+}
+
+// isHeld reports whether the check waits for its cgroup or its priority to
+// be set before executing its command
+func (p Process) isHeld() bool {
+	return p.Cgroup != "" || p.hasPriority()
+}
+
+// setPriority applies the nice and the I/O priority to the process group
+// of the check
+func (p Process) setPriority(pgid int) error {
+	if p.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pgid, p.Nice); err != nil &&
+			err != syscall.ESRCH {
+			return fmt.Errorf("Can't set the nice value: %s", err.Error())
+		}
+	}
+
+	if p.IOClass == "" {
+		return nil
+	}
+
+	priority := 4
+
+	if p.IOPriority != nil {
+		priority = *p.IOPriority
+	}
+
+	if err := setIOPriority(pgid, ioClasses[p.IOClass], priority); err != nil {
+		return fmt.Errorf("Can't set the I/O priority: %s", err.Error())
+	}
+
+	return nil
+}
+
+// cpuTimeExceeded reports whether the check was killed by the CPU time
+// limit, directly or as the child of the shell. SIGKILL is sent at the hard
+// limit, so it is attributed to the limit only once the soft one is reached.
+func (p Process) cpuTimeExceeded(state *os.ProcessState) bool {
+	if p.CPUTime == 0 || state == nil || state.Success() {
+		return false
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+
+	if !ok {
+		return false
+	}
+
+	signal := status.Signal()
+
+	if !status.Signaled() {
+		signal = syscall.Signal(status.ExitStatus() - 128)
+	}
+
+	switch signal {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		return (state.UserTime() + state.SystemTime()).Seconds() >= float64(p.CPUTime)
+	}
+
+	return false
+}
+
+func hasField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
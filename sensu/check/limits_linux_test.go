@@ -0,0 +1,145 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func TestProcessValidateLinux(t *testing.T) {
+	priority := 7
+
+	for _, tCase := range []struct {
+		process Process
+		err     string
+	}{
+		{Process{Nice: 19, IOClass: "idle"}, ""},
+		{Process{IOClass: "best-effort", IOPriority: &priority}, ""},
+		{Process{Cgroup: "sensu"}, "The cgroup has to be an absolute path: sensu"},
+		{Process{Cgroup: "/nonexistent"}, "Not a cgroup v2 directory: /nonexistent"},
+	} {
+		err := tCase.process.Validate()
+
+		if tCase.err == "" && err != nil {
+			t.Errorf("Expected a nil error for %+v but got \"%s\" instead!", tCase.process, err)
+		} else if tCase.err != "" && (err == nil || err.Error() != tCase.err) {
+			t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+		}
+	}
+}
+
+func TestProcessValidateCgroup(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu io pids\n"), 0644)
+
+	if err := (Process{Cgroup: dir}).Validate(); err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	err := (Process{Cgroup: dir, MemoryMax: 1 << 20}).Validate()
+
+	if err == nil || !strings.HasPrefix(err.Error(), "The memory controller isn't available") {
+		t.Errorf("Expected a missing memory controller error but got \"%v\" instead!", err)
+	}
+}
+
+func TestIOPriority(t *testing.T) {
+	for _, tCase := range []struct {
+		process Process
+		output  string
+	}{
+		{Process{IOClass: "idle"}, "idle\n"},
+		{Process{IOClass: "best-effort"}, "best-effort: prio 4\n"},
+	} {
+		r := (&ExternalCheck{
+			Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "ionice"}},
+			Direct:  true,
+			Process: tCase.process,
+		}).Execute()
+
+		if r.Status != stdCheck.Success || r.Output != tCase.output {
+			t.Errorf(
+				"Expected \"%s\" for %+v but got %d \"%s\" instead!",
+				tCase.output,
+				tCase.process,
+				r.Status,
+				r.Output,
+			)
+		}
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	root := newTempDir(t)
+	defer os.RemoveAll(root)
+
+	g, err := newCgroup(root, "disk/usage", 0)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if !strings.HasPrefix(g.path, filepath.Join(root, "disk_usage-")) {
+		t.Errorf("Expected the cgroup to be a child of %s but got %s instead!", root, g.path)
+	}
+
+	if g.oomKilled() {
+		t.Error("Expected no OOM kill without memory events!")
+	}
+
+	g.remove()
+
+	if _, err := os.Stat(g.path); !os.IsNotExist(err) {
+		t.Errorf("Expected the cgroup to be removed but got \"%v\" instead!", err)
+	}
+
+	if _, err := newCgroup(root, "disk", 1<<20); err == nil {
+		t.Error("Expected an error without memory controller but got nil instead!")
+	}
+
+	for _, tCase := range []struct {
+		events string
+		killed bool
+	}{
+		{"low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n", false},
+		{"low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n", true},
+	} {
+		ioutil.WriteFile(filepath.Join(root, "memory.events"), []byte(tCase.events), 0644)
+
+		if killed := (&cgroup{path: root}).oomKilled(); killed != tCase.killed {
+			t.Errorf("Expected OOM kill to be %t but got %t instead!", tCase.killed, killed)
+		}
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	root := os.Getenv("SENSU_TEST_CGROUP")
+
+	if root == "" {
+		t.Skip("SENSU_TEST_CGROUP isn't set to a cgroup v2 directory")
+	}
+
+	process := Process{Cgroup: root, MemoryMax: 16 << 20}
+
+	if err := process.Validate(); err != nil {
+		t.Skipf("The cgroup can't be used: %s", err)
+	}
+
+	r := (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{
+			Check: &stdCheck.Check{Name: "memory", Command: "head -c 64m /dev/zero | tail"},
+		},
+		Process: process,
+	}).Execute()
+
+	if r.Status != stdCheck.Error ||
+		r.Output != "Execution killed: memory limit of 16777216 bytes exceeded" {
+		t.Errorf("Expected the memory limit to be exceeded but got %d \"%s\" instead!", r.Status, r.Output)
+	}
+}
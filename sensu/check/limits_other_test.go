@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package check
+
+import "testing"
+
+func TestProcessValidateOther(t *testing.T) {
+	for _, tCase := range []struct {
+		process Process
+		err     string
+	}{
+		{Process{Cgroup: "/sys/fs/cgroup/sensu"}, "The cgroup isn't supported on this platform"},
+		{Process{IOClass: "idle"}, "The io_class isn't supported on this platform"},
+	} {
+		if err := tCase.process.Validate(); err == nil || err.Error() != tCase.err {
+			t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package check
+
+import (
+	"io/ioutil"
+	"testing"
+
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func TestProcessValidate(t *testing.T) {
+	priority, invalidPriority := 7, 8
+
+	for _, tCase := range []struct {
+		process Process
+		err     string
+	}{
+		{Process{}, ""},
+		{Process{Nice: 19, CPUTime: 10}, ""},
+		{Process{Nice: 20}, "Invalid nice value: 20"},
+		{Process{IOClass: "fast"}, "Invalid io_class: fast"},
+		{Process{IOPriority: &priority}, "The io_priority requires an io_class"},
+		{Process{IOClass: "realtime", IOPriority: &invalidPriority}, "Invalid io_priority: 8"},
+		{Process{MemoryMax: 1 << 20}, "The memory_max requires a cgroup"},
+	} {
+		err := tCase.process.Validate()
+
+		if tCase.err == "" && err != nil {
+			t.Errorf("Expected a nil error for %+v but got \"%s\" instead!", tCase.process, err)
+		} else if tCase.err != "" && (err == nil || err.Error() != tCase.err) {
+			t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, tCase := range []struct {
+		command string
+		direct  bool
+		process Process
+		status  stdCheck.ExitStatus
+		output  string
+	}{
+		{"ulimit -n; ulimit -v", false, Process{OpenFiles: 64, AddressSpace: 1 << 30}, 0, "64\n1048576\n"},
+		{"ulimit -S -t; ulimit -H -t", false, Process{CPUTime: 5}, 0, "5\n6\n"},
+		{
+			"while :; do :; done",
+			false,
+			Process{CPUTime: 1},
+			stdCheck.Error,
+			"Execution killed: CPU time limit of 1s exceeded",
+		},
+		{"exit 2", false, Process{CPUTime: 1}, 2, ""},
+		{"nice", false, Process{Nice: 5}, 0, "5\n"},
+		{"nice", true, Process{Nice: 5, CPUTime: 5}, 0, "5\n"},
+	} {
+		r := (&ExternalCheck{
+			Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: tCase.command}},
+			Direct:  tCase.direct,
+			Process: tCase.process,
+		}).Execute()
+
+		if r.Status != tCase.status || r.Output != tCase.output {
+			t.Errorf(
+				"Expected %d \"%s\" for %s but got %d \"%s\" instead!",
+				tCase.status,
+				tCase.output,
+				tCase.command,
+				r.Status,
+				r.Output,
+			)
+		}
+	}
+}
+
+func newTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sensu-check")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	return dir
+}
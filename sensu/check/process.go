@@ -0,0 +1,243 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Process configures the processes of the external checks, the settings of
+// a check definition override the global ones
+type Process struct {
+	// Name or ID of the user the check runs as
+	User string `json:"user,omitempty"`
+	// Name or ID of the group the check runs as, the user primary group by
+	// default
+	Group string `json:"group,omitempty"`
+	// Names or IDs of the supplementary groups, the ones of the client are
+	// dropped when the check runs as another user
+	Groups []string `json:"groups,omitempty"`
+	// Seconds of CPU time after which the check is killed
+	CPUTime uint64 `json:"cpu_time,omitempty"`
+	// Bytes of virtual memory the check can allocate
+	AddressSpace uint64 `json:"address_space,omitempty"`
+	// Number of files the check can open
+	OpenFiles uint64 `json:"open_files,omitempty"`
+	// Scheduling priority, from -20 to 19
+	Nice int `json:"nice,omitempty"`
+	// I/O scheduling class: realtime, best-effort or idle
+	IOClass string `json:"io_class,omitempty"`
+	// I/O priority in the class, from 0 to 7
+	IOPriority *int `json:"io_priority,omitempty"`
+	// cgroup v2 directory the check runs in, in a child group of its own
+	Cgroup string `json:"cgroup,omitempty"`
+	// Bytes of memory of the check cgroup
+	MemoryMax uint64 `json:"memory_max,omitempty"`
+	// Variables set in the environment of the check, the null ones are
+	// unset
+	Env map[string]*string `json:"env,omitempty"`
+	// Starts the check with the client PATH and the Env variables only
+	CleanEnv bool `json:"clean_env,omitempty"`
+	// Working directory of the check
+	Cwd string `json:"cwd,omitempty"`
+	// Directories prepended to the PATH of the check
+	PluginPaths []string `json:"plugin_paths,omitempty"`
+}
+
+// Merge returns the settings overridden by the ones set in o
+func (p Process) Merge(o Process) Process {
+	if o.User != "" {
+		p.User = o.User
+	}
+
+	if o.Group != "" {
+		p.Group = o.Group
+	}
+
+	if o.Groups != nil {
+		p.Groups = o.Groups
+	}
+
+	for _, v := range []struct{ p, o *uint64 }{
+		{&p.CPUTime, &o.CPUTime},
+		{&p.AddressSpace, &o.AddressSpace},
+		{&p.OpenFiles, &o.OpenFiles},
+		{&p.MemoryMax, &o.MemoryMax},
+	} {
+		if *v.o != 0 {
+			*v.p = *v.o
+		}
+	}
+
+	if o.Nice != 0 {
+		p.Nice = o.Nice
+	}
+
+	if o.IOClass != "" {
+		p.IOClass = o.IOClass
+	}
+
+	if o.IOPriority != nil {
+		p.IOPriority = o.IOPriority
+	}
+
+	if o.Cgroup != "" {
+		p.Cgroup = o.Cgroup
+	}
+
+	if o.Env != nil {
+		env := make(map[string]*string, len(p.Env)+len(o.Env))
+
+		for _, vars := range []map[string]*string{p.Env, o.Env} {
+			for k, v := range vars {
+				env[k] = v
+			}
+		}
+
+		p.Env = env
+	}
+
+	if o.CleanEnv {
+		p.CleanEnv = true
+	}
+
+	if o.Cwd != "" {
+		p.Cwd = o.Cwd
+	}
+
+	if o.PluginPaths != nil {
+		p.PluginPaths = o.PluginPaths
+	}
+
+	return p
+}
+
+// Credential resolves the user and the groups the check runs as, it is nil
+// if the check runs as the client user and groups
+func (p Process) Credential() (*syscall.Credential, error) {
+	if p.User == "" && p.Group == "" && p.Groups == nil {
+		return nil, nil
+	}
+
+	credential := &syscall.Credential{
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	}
+
+	var u *user.User
+
+	if p.User != "" {
+		var err error
+
+		if u, err = lookupUser(p.User); err != nil {
+			return nil, err
+		}
+
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+
+		credential.Uid, credential.Gid = uint32(uid), uint32(gid)
+	}
+
+	if p.Group != "" {
+		gid, err := lookupGroup(p.Group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Gid = gid
+	}
+
+	for _, name := range p.Groups {
+		gid, err := lookupGroup(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Groups = append(credential.Groups, gid)
+	}
+
+	// Nothing to switch, the client groups are kept
+	if credential.Uid == uint32(os.Getuid()) &&
+		credential.Gid == uint32(os.Getgid()) &&
+		p.Groups == nil {
+		return nil, nil
+	}
+
+	// The user keeps its supplementary groups unless they are configured,
+	// like initgroups does
+	if u != nil && p.Groups == nil {
+		groups, err := userGroups(u)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Groups = groups
+	}
+
+	return credential, nil
+}
+
+func userGroups(u *user.User) ([]uint32, error) {
+	gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+
+	// The users without passwd entry only have their own group
+	if u.Username == "" {
+		return []uint32{uint32(gid)}, nil
+	}
+
+	ids, err := u.GroupIds()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't resolve the groups of the user %s: %s", u.Username, err.Error())
+	}
+
+	groups := make([]uint32, 0, len(ids))
+
+	for _, id := range ids {
+		gid, _ := strconv.ParseUint(id, 10, 32)
+		groups = append(groups, uint32(gid))
+	}
+
+	return groups, nil
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+
+		// The users without passwd entry run with their own ID as group
+		return &user.User{Uid: name, Gid: name}, nil
+	}
+
+	u, err := user.Lookup(name)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unknown user: %s", name)
+	}
+
+	return u, nil
+}
+
+func lookupGroup(name string) (uint32, error) {
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+
+	g, err := user.LookupGroup(name)
+
+	if err != nil {
+		return 0, fmt.Errorf("Unknown group: %s", name)
+	}
+
+	gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+
+	return uint32(gid), nil
+}
@@ -0,0 +1,112 @@
+package check
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func TestProcessMerge(t *testing.T) {
+	global := Process{User: "sensu", Group: "sensu", Groups: []string{"adm"}}
+
+	for _, tCase := range []struct {
+		process  Process
+		expected Process
+	}{
+		{Process{}, global},
+		{
+			Process{User: "nobody"},
+			Process{User: "nobody", Group: "sensu", Groups: []string{"adm"}},
+		},
+		{
+			Process{Group: "nogroup", Groups: []string{}},
+			Process{User: "sensu", Group: "nogroup", Groups: []string{}},
+		},
+	} {
+		if p := global.Merge(tCase.process); !reflect.DeepEqual(p, tCase.expected) {
+			t.Errorf("Expected %+v but got %+v instead!", tCase.expected, p)
+		}
+	}
+}
+
+func TestProcessCredential(t *testing.T) {
+	for _, tCase := range []struct {
+		process Process
+		uid     uint32
+		gid     uint32
+		groups  []uint32
+		err     string
+	}{
+		{Process{User: "nobody"}, 65534, 65534, []uint32{65534}, ""},
+		{Process{User: "nobody", Groups: []string{}}, 65534, 65534, nil, ""},
+		{Process{User: "65534", Group: "0", Groups: []string{"nogroup", "4"}}, 65534, 0, []uint32{65534, 4}, ""},
+		{Process{User: "424242"}, 424242, 424242, []uint32{424242}, ""},
+		{Process{User: "nosuchuser"}, 0, 0, nil, "Unknown user: nosuchuser"},
+		{Process{User: "nobody", Groups: []string{"nosuchgroup"}}, 0, 0, nil, "Unknown group: nosuchgroup"},
+	} {
+		credential, err := tCase.process.Credential()
+
+		if tCase.err != "" {
+			if err == nil || err.Error() != tCase.err {
+				t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		if credential.Uid != tCase.uid || credential.Gid != tCase.gid ||
+			!reflect.DeepEqual(credential.Groups, tCase.groups) {
+			t.Errorf(
+				"Expected %d:%d %v but got %d:%d %v instead!",
+				tCase.uid,
+				tCase.gid,
+				tCase.groups,
+				credential.Uid,
+				credential.Gid,
+				credential.Groups,
+			)
+		}
+	}
+}
+
+func TestProcessWithoutCredential(t *testing.T) {
+	for _, p := range []Process{
+		{},
+		{User: strconv.Itoa(os.Getuid()), Group: strconv.Itoa(os.Getgid())},
+	} {
+		if credential, err := p.Credential(); credential != nil || err != nil {
+			t.Errorf("Expected no credential for %+v but got %+v, %v instead!", p, credential, err)
+		}
+	}
+}
+
+func TestExternalCheckAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Switching user requires root")
+	}
+
+	r := (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "id -u; id -G; echo $HOME $USER"}},
+		Process: Process{User: "nobody", Groups: []string{}},
+	}).Execute()
+
+	if r.Status != stdCheck.Success || r.Output != "65534\n65534\n/nonexistent nobody\n" {
+		t.Errorf("Expected the check to run as nobody but got %d \"%s\" instead!", r.Status, r.Output)
+	}
+
+	r = (&ExternalCheck{
+		Request: &stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "id -u"}},
+		Process: Process{User: "nosuchuser"},
+	}).Execute()
+
+	if r.Status != stdCheck.Error || r.Output != "Unknown user: nosuchuser" {
+		t.Errorf("Expected an unknown user error but got %d \"%s\" instead!", r.Status, r.Output)
+	}
+}
@@ -1,9 +1,19 @@
 package check
 
-import stdCheck "github.com/upfluence/sensu-go/sensu/check"
+import (
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
 
 var Store = make(map[string]Check)
 
 type Check interface {
 	Execute() stdCheck.CheckOutput
 }
+
+// ClockedCheck is implemented by the checks which can be timed by a given
+// clock, the client runs them on its own clock
+type ClockedCheck interface {
+	Check
+	ExecuteWithClock(clock.Clock) stdCheck.CheckOutput
+}
@@ -3,31 +3,53 @@ package sensu
 import (
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/upfluence/goutils/log"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	"github.com/upfluence/sensu-go/sensu/transport"
 )
 
-const (
-	currentVersion    = "1.2.0"
-	connectionTimeout = 5 * time.Second
-)
+const connectionTimeout = 5 * time.Second
 
 type Client struct {
 	Transport transport.Transport
 	Config    *Config
+	// Clock schedules the keepalives and the standalone checks, times the
+	// checks and their timeouts, the real clock is used if nil
+	Clock clock.Clock
+
+	stopChan chan bool
 }
 
 func NewClient(transport transport.Transport, cfg *Config) *Client {
 	client := Client{
-		transport,
-		cfg,
+		Transport: transport,
+		Config:    cfg,
+		stopChan:  make(chan bool, 1),
 	}
 
 	return &client
 }
 
+func (c *Client) clock() clock.Clock {
+	if c == nil || c.Clock == nil {
+		return clock.Real
+	}
+
+	return c.Clock
+}
+
+// Stop makes Start return, like SIGTERM does, the client has to be created
+// with NewClient
+func (c *Client) Stop() {
+	select {
+	case c.stopChan <- true:
+	default:
+	}
+}
+
 func (c *Client) buildProcessors() []Processor {
 	processors := []Processor{NewKeepAlive(c)}
 
@@ -35,9 +57,9 @@ func (c *Client) buildProcessors() []Processor {
 		processors = append(processors, NewSubscriber(s, c))
 	}
 
-	for _, check := range c.Config.Checks() {
+	for _, check := range c.Config.CheckDefinitions() {
 		if check.Standalone {
-			processors = append(processors, NewStandalone(check, c))
+			processors = append(processors, NewStandaloneFromDefinition(check, c))
 		}
 	}
 
@@ -45,17 +67,30 @@ func (c *Client) buildProcessors() []Processor {
 }
 
 func (c *Client) Start() error {
-	sig := make(chan os.Signal)
-	signal.Notify(sig, os.Kill, os.Interrupt)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+
+	if l, err := c.startHTTPSocket(); err != nil {
+		log.Errorf("Failed to start the HTTP socket: %s", err.Error())
+	} else if l != nil {
+		defer l.Close()
+	}
 
 	for {
 		c.Transport.Connect()
 
 		for !c.Transport.IsConnected() {
+			timer := c.clock().NewTimer(connectionTimeout)
+
 			select {
-			case <-time.After(connectionTimeout):
+			case <-timer.C():
 				c.Transport.Connect()
 			case <-sig:
+				timer.Stop()
+				return c.Transport.Close()
+			case <-c.stopChan:
+				timer.Stop()
 				return c.Transport.Close()
 			}
 		}
@@ -69,11 +104,11 @@ func (c *Client) Start() error {
 		case s := <-sig:
 			log.Noticef("Signal %s received", s.String())
 
-			for _, processor := range processors {
-				processor.Close()
-			}
+			return c.stop(processors)
+		case <-c.stopChan:
+			log.Notice("Stop requested")
 
-			return c.Transport.Close()
+			return c.stop(processors)
 		case <-c.Transport.GetClosingChan():
 			log.Notice("Transport disconnected")
 
@@ -85,3 +120,17 @@ func (c *Client) Start() error {
 		}
 	}
 }
+
+// stop closes the processors and the transport, the client is deregistered
+// before if it is configured to
+func (c *Client) stop(processors []Processor) error {
+	for _, processor := range processors {
+		processor.Close()
+	}
+
+	if err := c.deregister(); err != nil {
+		log.Errorf("Failed to deregister: %s", err.Error())
+	}
+
+	return c.Transport.Close()
+}
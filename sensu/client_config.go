@@ -0,0 +1,259 @@
+package sensu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/upfluence/sensu-go/sensu/client"
+)
+
+const clientAttributeEnvPrefix = "SENSU_CLIENT_ATTR_"
+
+// clientConfigKeys lists the client attributes handled by clientConfig, any
+// other attribute of the client definition is a custom attribute
+var clientConfigKeys = []string{
+	"name",
+	"address",
+	"subscriptions",
+	"default_subscription",
+	"keepalive",
+	"deregister",
+	"deregistration",
+	"facts",
+	"http_socket",
+	"command_policy",
+	"redact",
+}
+
+// clientConfig extends the sensu-go client definition with the client
+// attributes supported by this client and the custom ones, which are sent
+// along with the keepalives. Facts lists the facts discovered and sent
+// along with the keepalives, see the facts package.
+type clientConfig struct {
+	*client.Client
+	DefaultSubscription *bool                  `json:"default_subscription,omitempty"`
+	KeepAlive           *keepAliveConfig       `json:"keepalive,omitempty"`
+	Deregister          bool                   `json:"deregister,omitempty"`
+	Deregistration      map[string]interface{} `json:"deregistration,omitempty"`
+	Facts               []string               `json:"facts,omitempty"`
+	HTTPSocket          *httpSocketConfig      `json:"http_socket,omitempty"`
+	CommandPolicy       *commandPolicy         `json:"command_policy,omitempty"`
+	Redact              []string               `json:"redact,omitempty"`
+	Attributes          map[string]interface{} `json:"-"`
+}
+
+func (c *clientConfig) UnmarshalJSON(b []byte) error {
+	type plain clientConfig
+
+	var p plain
+
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, &p.Attributes); err != nil {
+		return err
+	}
+
+	for _, k := range clientConfigKeys {
+		delete(p.Attributes, k)
+	}
+
+	if p.Client == nil {
+		p.Client = &client.Client{}
+	}
+
+	*c = clientConfig(p)
+
+	return nil
+}
+
+// defaultSubscription reports whether the client:name subscription has to be
+// added, it is enabled unless explicitly turned off
+func (c *clientConfig) defaultSubscription() bool {
+	return c.DefaultSubscription == nil || *c.DefaultSubscription
+}
+
+// attributes returns the full client definition, custom attributes included
+func (c *clientConfig) attributes() map[string]interface{} {
+	m := make(map[string]interface{}, len(c.Attributes)+len(clientConfigKeys))
+
+	for k, v := range c.Attributes {
+		m[k] = v
+	}
+
+	m["name"] = c.Name
+	m["address"] = c.Address
+	m["subscriptions"] = c.Subscriptions
+
+	if c.KeepAlive != nil {
+		m["keepalive"] = c.KeepAlive
+	}
+
+	if c.Deregister {
+		m["deregister"] = true
+	}
+
+	if c.Deregistration != nil {
+		m["deregistration"] = c.Deregistration
+	}
+
+	if c.HTTPSocket != nil {
+		m["http_socket"] = c.HTTPSocket
+	}
+
+	if c.Redact != nil {
+		m["redact"] = c.Redact
+	}
+
+	return m
+}
+
+func (c *clientConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.attributes())
+}
+
+// applyEnv overrides the client definition with the settings provided
+// through environment variables
+func (c *clientConfig) applyEnv() error {
+	if v := os.Getenv("SENSU_CLIENT_NAME"); v != "" {
+		c.Name = v
+	}
+
+	if v := fetchEnv("SENSU_CLIENT_ADDRESS", "SENSU_ADDRESS"); v != "" {
+		c.Address = v
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_SUBSCRIPTIONS"); v != "" {
+		c.Subscriptions = split(v, ",")
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_DEFAULT_SUBSCRIPTION"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+
+		if err != nil {
+			return fmt.Errorf("Invalid default subscription flag: %s", v)
+		}
+
+		c.DefaultSubscription = &enabled
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_DEREGISTER"); v != "" {
+		deregister, err := strconv.ParseBool(v)
+
+		if err != nil {
+			return fmt.Errorf("Invalid deregister flag: %s", v)
+		}
+
+		c.Deregister = deregister
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_DEREGISTRATION_HANDLER"); v != "" {
+		if c.Deregistration == nil {
+			c.Deregistration = make(map[string]interface{})
+		}
+
+		c.Deregistration["handler"] = v
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_FACTS"); v != "" {
+		c.Facts = split(v, ",")
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_REDACT"); v != "" {
+		c.Redact = split(v, ",")
+	}
+
+	keepAlive := &keepAliveConfig{}
+
+	if c.KeepAlive != nil {
+		keepAlive = c.KeepAlive
+	}
+
+	if err := keepAlive.applyEnv(); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(keepAlive, &keepAliveConfig{}) {
+		c.KeepAlive = keepAlive
+	}
+
+	httpSocket := &httpSocketConfig{}
+
+	if c.HTTPSocket != nil {
+		httpSocket = c.HTTPSocket
+	}
+
+	if err := httpSocket.applyEnv(); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(httpSocket, &httpSocketConfig{}) {
+		c.HTTPSocket = httpSocket
+	}
+
+	policy := &commandPolicy{}
+
+	if c.CommandPolicy != nil {
+		policy = c.CommandPolicy
+	}
+
+	if err := policy.applyEnv(); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(policy, &commandPolicy{}) {
+		c.CommandPolicy = policy
+	}
+
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, clientAttributeEnvPrefix) {
+			continue
+		}
+
+		kv := strings.SplitN(env[len(clientAttributeEnvPrefix):], "=", 2)
+
+		if kv[0] == "" || len(kv) != 2 {
+			continue
+		}
+
+		v, err := parseAttributeValue(kv[1])
+
+		if err != nil {
+			return fmt.Errorf(
+				"Invalid value for %s%s: %s",
+				clientAttributeEnvPrefix,
+				kv[0],
+				err.Error(),
+			)
+		}
+
+		if c.Attributes == nil {
+			c.Attributes = make(map[string]interface{})
+		}
+
+		c.Attributes[strings.ToLower(kv[0])] = v
+	}
+
+	return nil
+}
+
+// parseAttributeValue decodes JSON objects and arrays, any other value is
+// kept as a string
+func parseAttributeValue(v string) (interface{}, error) {
+	if !strings.HasPrefix(v, "{") && !strings.HasPrefix(v, "[") {
+		return v, nil
+	}
+
+	var value interface{}
+
+	if err := json.Unmarshal([]byte(v), &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
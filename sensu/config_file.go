@@ -0,0 +1,304 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+type configDecoder func([]byte) (map[string]interface{}, error)
+
+// configDecoders maps the supported config file extensions to their decoder,
+// files with an unknown extension are read as JSON
+var configDecoders = map[string]configDecoder{
+	".json": decodeJSONConfig,
+	".yaml": decodeYAMLConfig,
+	".yml":  decodeYAMLConfig,
+	".toml": decodeTOMLConfig,
+}
+
+func decodeJSONConfig(buf []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func decodeYAMLConfig(buf []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+
+	return normalizeConfigValue(m).(map[string]interface{}), nil
+}
+
+func decodeTOMLConfig(buf []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+
+	if _, err := toml.Decode(string(buf), &m); err != nil {
+		return nil, err
+	}
+
+	return normalizeConfigValue(m).(map[string]interface{}), nil
+}
+
+// normalizeConfigValue converts the maps and slices produced by the YAML and
+// TOML decoders to the types produced by encoding/json, so every format can
+// be merged and re-encoded the same way
+func normalizeConfigValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+
+		for k, e := range vv {
+			m[fmt.Sprint(k)] = normalizeConfigValue(e)
+		}
+
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+
+		for k, e := range vv {
+			m[k] = normalizeConfigValue(e)
+		}
+
+		return m
+	case []map[string]interface{}:
+		s := make([]interface{}, len(vv))
+
+		for i, e := range vv {
+			s[i] = normalizeConfigValue(e)
+		}
+
+		return s
+	case []interface{}:
+		s := make([]interface{}, len(vv))
+
+		for i, e := range vv {
+			s[i] = normalizeConfigValue(e)
+		}
+
+		return s
+	}
+
+	return v
+}
+
+// configFiles returns the files to load for a path, a directory is expanded
+// to the files it contains with a supported extension, in lexical order
+func configFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if _, ok := configDecoders[strings.ToLower(filepath.Ext(entry.Name()))]; ok {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func readConfigFile(file string) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, ok := configDecoders[strings.ToLower(filepath.Ext(file))]
+
+	if !ok {
+		decoder = decodeJSONConfig
+	}
+
+	m, err := decoder(buf)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %s", file, err.Error())
+	}
+
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+
+	return m, nil
+}
+
+// deepMerge emulates ruby client behavior when loading several files:
+// hashes are merged recursively, arrays are concatenated without duplicates
+// and any other value is overridden
+func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		switch sv := v.(type) {
+		case map[string]interface{}:
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				dst[k] = deepMerge(dv, sv)
+				continue
+			}
+		case []interface{}:
+			if dv, ok := dst[k].([]interface{}); ok {
+				dst[k] = mergeSlices(dv, sv)
+				continue
+			}
+		}
+
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// mergeConfig deep merges src into dst, the checks with the same name are
+// merged into one definition instead of being concatenated
+func mergeConfig(dst, src map[string]interface{}) map[string]interface{} {
+	dstChecks, dok := dst["checks"].([]interface{})
+	srcChecks, sok := src["checks"].([]interface{})
+
+	if !dok || !sok {
+		return deepMerge(dst, src)
+	}
+
+	rest := make(map[string]interface{}, len(src))
+
+	for k, v := range src {
+		if k != "checks" {
+			rest[k] = v
+		}
+	}
+
+	dst = deepMerge(dst, rest)
+	dst["checks"] = mergeCheckMaps(dstChecks, srcChecks)
+
+	return dst
+}
+
+// mergeCheckMaps deep merges the checks of src into the ones of dst with
+// the same name, the other ones are appended
+func mergeCheckMaps(dst, src []interface{}) []interface{} {
+	for _, v := range src {
+		check, ok := v.(map[string]interface{})
+
+		if !ok || check["name"] == nil {
+			dst = mergeSlices(dst, []interface{}{v})
+			continue
+		}
+
+		found := false
+
+		for i, e := range dst {
+			if m, ok := e.(map[string]interface{}); ok && m["name"] == check["name"] {
+				dst[i] = deepMerge(m, check)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			dst = append(dst, v)
+		}
+	}
+
+	return dst
+}
+
+func mergeSlices(dst, src []interface{}) []interface{} {
+	for _, v := range src {
+		found := false
+
+		for _, e := range dst {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			dst = append(dst, v)
+		}
+	}
+
+	return dst
+}
+
+// loadConfigPayload reads and merges the given files and directories, in
+// order, expands the environment variables references and decodes the
+// result into a configPayload
+func loadConfigPayload(paths ...string) (*configPayload, error) {
+	merged := map[string]interface{}{}
+
+	for _, path := range paths {
+		files, err := configFiles(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			m, err := readConfigFile(file)
+
+			if err != nil {
+				return nil, err
+			}
+
+			merged = mergeConfig(merged, m)
+		}
+	}
+
+	if err := interpolateConfig(merged); err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(merged)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var payload configPayload
+
+	if err := json.Unmarshal(buf, &payload); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &payload.settings); err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
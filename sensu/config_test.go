@@ -8,7 +8,7 @@ import (
 	"testing"
 
 	"github.com/upfluence/goutils/testing/utils"
-	"github.com/upfluence/sensu-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/check"
 	stdClient "github.com/upfluence/sensu-go/sensu/client"
 )
 
@@ -102,7 +102,9 @@ func validateClient(
 func TestClientFromConfig(t *testing.T) {
 	dummyClient := newDummyClient()
 
-	config := Config{config: &configPayload{Client: dummyClient}}
+	config := Config{
+		config: &configPayload{Client: &clientConfig{Client: dummyClient}},
+	}
 
 	validateClient(config.Client(), dummyClient, t)
 }
@@ -146,7 +148,7 @@ func TestChecksFromConfig(t *testing.T) {
 	expectedCheckCount := 2
 	config := Config{
 		config: &configPayload{
-			Checks: []*check.Check{&check.Check{}, &check.Check{}},
+			Checks: []*check.Definition{&check.Definition{}, &check.Definition{}},
 		},
 	}
 
@@ -268,3 +270,55 @@ func validateClientSubscriptions(s1 []string, s2 []string, t *testing.T) {
 		)
 	}
 }
+
+func TestSubscriptionOrder(t *testing.T) {
+	for _, tCase := range []struct {
+		in  string
+		out []string
+	}{
+		{
+			"testdata/client-dupeSubs.json",
+			strings.Split("duplicate,unique,client:foo", ","),
+		},
+		{
+			"testdata/client-uniqueSubs.json",
+			strings.Split("unique1,unique2,unique3,client:foo", ","),
+		},
+		{
+			"testdata/client-noDefaultSub.json",
+			[]string{"unique"},
+		},
+	} {
+		cfg, err := NewConfigFromFile(nil, tCase.in)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		if !reflect.DeepEqual(cfg.Client().Subscriptions, tCase.out) {
+			t.Errorf(
+				"Expected client subscriptions to be \"%#v\" but got \"%#v\" instead!",
+				tCase.out,
+				cfg.Client().Subscriptions,
+			)
+		}
+	}
+}
+
+func TestDefaultSubscriptionFromEnvVar(t *testing.T) {
+	os.Setenv("SENSU_CLIENT_DEFAULT_SUBSCRIPTION", "false")
+	defer os.Unsetenv("SENSU_CLIENT_DEFAULT_SUBSCRIPTION")
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-noSubs.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if len(cfg.Client().Subscriptions) != 0 {
+		t.Errorf(
+			"Expected no client subscriptions but got \"%#v\" instead!",
+			cfg.Client().Subscriptions,
+		)
+	}
+}
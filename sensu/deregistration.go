@@ -0,0 +1,55 @@
+package sensu
+
+import (
+	"encoding/json"
+
+	"github.com/upfluence/goutils/log"
+)
+
+const (
+	deregistrationCheckName = "deregistration"
+	deregistrationOutput    = "client initiated deregistration"
+	// deregistrationHandler is the default handler of the ruby client
+	deregistrationHandler = "deregistration"
+)
+
+// deregister emulates ruby client behavior on graceful shutdown when the
+// client deregister attribute is set: it publishes a deregistration check
+// result, the deregistration client attributes override the default ones
+func (c *Client) deregister() error {
+	cfg := c.Config.client()
+
+	if !cfg.Deregister {
+		return nil
+	}
+
+	now := c.clock().Now().Unix()
+	result := map[string]interface{}{
+		"name":     deregistrationCheckName,
+		"output":   deregistrationOutput,
+		"status":   1,
+		"handler":  deregistrationHandler,
+		"issued":   now,
+		"executed": now,
+	}
+
+	for k, v := range cfg.Deregistration {
+		result[k] = v
+	}
+
+	p, err := json.Marshal(
+		map[string]interface{}{"client": cfg.Name, "check": result},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if p, err = c.sign(p); err != nil {
+		return err
+	}
+
+	log.Noticef("Payload sent: %s", c.redactPayload(p))
+
+	return c.Transport.Publish("direct", "results", "", p)
+}
@@ -0,0 +1,101 @@
+package sensu
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/upfluence/goutils/testing/utils"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func TestDeregisterDisabled(t *testing.T) {
+	c := newTestClient(&configPayload{})
+	transport := c.Transport.(*memory.Transport)
+
+	if err := c.deregister(); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	if lastMessage(transport) != nil {
+		t.Errorf(
+			"Expected nothing to be published but got %s instead!",
+			lastMessage(transport).Body,
+		)
+	}
+}
+
+type deregistrationPayload struct {
+	Client string `json:"client"`
+	Check  struct {
+		Name    string `json:"name"`
+		Output  string `json:"output"`
+		Status  int    `json:"status"`
+		Handler string `json:"handler"`
+	} `json:"check"`
+}
+
+func TestDeregisterDefaultHandler(t *testing.T) {
+	c := newTestClient(
+		&configPayload{
+			Client: &clientConfig{
+				Client:     &stdClient.Client{Name: "Test"},
+				Deregister: true,
+			},
+		},
+	)
+
+	if err := c.deregister(); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	var payload deregistrationPayload
+
+	if err := json.Unmarshal(lastMessage(c.Transport.(*memory.Transport)).Body, &payload); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(payload.Check.Handler, deregistrationHandler, "handler", t)
+}
+
+func TestDeregister(t *testing.T) {
+	c := newTestClient(
+		&configPayload{
+			Client: &clientConfig{
+				Client:         &stdClient.Client{Name: "Test"},
+				Deregister:     true,
+				Deregistration: map[string]interface{}{"handler": "ec2_deregister"},
+			},
+		},
+	)
+	transport := c.Transport.(*memory.Transport)
+
+	if err := c.deregister(); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	if lastMessage(transport) == nil {
+		t.Fatal("Expected a check result to be published but got nothing!")
+	}
+
+	if lastMessage(transport).ExchangeName != "results" {
+		t.Errorf(
+			"Expected exchange name to be \"results\" but got \"%s\" instead!",
+			lastMessage(transport).ExchangeName,
+		)
+	}
+
+	var payload deregistrationPayload
+
+	if err := json.Unmarshal(lastMessage(transport).Body, &payload); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	if payload.Client != "Test" ||
+		payload.Check.Name != deregistrationCheckName ||
+		payload.Check.Output != deregistrationOutput ||
+		payload.Check.Status != 1 ||
+		payload.Check.Handler != "ec2_deregister" {
+		t.Errorf("Unexpected deregistration payload: %+v", payload)
+	}
+}
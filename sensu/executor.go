@@ -2,9 +2,13 @@ package sensu
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/upfluence/goutils/log"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 )
 
 type CheckResponse struct {
@@ -12,22 +16,53 @@ type CheckResponse struct {
 	Client string               `json:"client"`
 }
 
+const policyViolationOutput = "Command rejected by the command policy: %s"
+
 var commandKeyError = errors.New("Command key not filled")
 
-func executeCheck(input *stdCheck.CheckRequest) (*stdCheck.CheckOutput, error) {
+// executeCheck runs the check of the request, its command has to comply
+// with the given policy, nil allows any command
+func executeCheck(
+	input *check.Request,
+	clk clock.Clock,
+	policy *commandPolicy,
+) (*stdCheck.CheckOutput, error) {
 	var output stdCheck.CheckOutput
 
 	if ch, ok := check.Store[input.Extension]; input.Extension != "" && ok {
-		output = ch.Execute()
+		output = executeWithClock(ch, clk)
 	} else if ch, ok := check.Store[input.Name]; ok {
-		output = ch.Execute()
+		output = executeWithClock(ch, clk)
 	} else if input.Command == "" {
 		return nil, commandKeyError
+	} else if err := policy.evaluate(input.Command, input.Process); err != nil {
+		log.Errorf("Command of the check %s rejected: %s", input.Name, err.Error())
+
+		output = stdCheck.CheckOutput{
+			Status:   stdCheck.Error,
+			Output:   fmt.Sprintf(policyViolationOutput, err.Error()),
+			Executed: clk.Now().Unix(),
+		}
 	} else {
-		output = (&check.ExternalCheck{Request: input}).Execute()
+		output = (&check.ExternalCheck{
+			Request: input.CheckRequest(),
+			Timeout: time.Duration(input.Timeout) * time.Second,
+			Direct:  policy.directExec(),
+			Process: input.Process,
+		}).ExecuteWithClock(clk)
 	}
 
-	output.CheckRequest = input
+	output.CheckRequest = input.CheckRequest()
 
 	return &output, nil
 }
+
+// executeWithClock runs the check on the given clock, the checks registered
+// by the users may not support it
+func executeWithClock(ch check.Check, clk clock.Clock) stdCheck.CheckOutput {
+	if c, ok := ch.(check.ClockedCheck); ok {
+		return c.ExecuteWithClock(clk)
+	}
+
+	return ch.Execute()
+}
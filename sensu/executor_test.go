@@ -2,18 +2,20 @@ package sensu
 
 import (
 	"testing"
+	"time"
 
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
 	"github.com/upfluence/sensu-client-go/sensu/handler"
 )
 
 func validateCheckOutput(
-	checkRequest *stdCheck.CheckRequest,
+	checkRequest *check.Request,
 	expectedOutput *stdCheck.CheckOutput,
 	t *testing.T) {
 
-	output, err := executeCheck(checkRequest)
+	output, err := executeCheck(checkRequest, clock.Real, nil)
 
 	if err != nil {
 		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
@@ -69,16 +71,66 @@ func TestExecuteExtensionCheck(t *testing.T) {
 		check.Store[test.check.Name] = test.extensionCheck
 
 		validateCheckOutput(
-			&stdCheck.CheckRequest{Check: test.check},
+			&check.Request{Definition: &check.Definition{Check: test.check}},
 			&stdCheck.CheckOutput{Status: 0, Output: test.output},
 			t,
 		)
 	}
 }
 
+type unclockedCheck struct{}
+
+func (unclockedCheck) Execute() stdCheck.CheckOutput {
+	return stdCheck.CheckOutput{Output: "unclocked", Executed: 42}
+}
+
+func TestExecuteCheckWithClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+
+	check.Store["clocked_check"] = &check.ExtensionCheck{Function: checkTestFunction}
+	check.Store["unclocked_check"] = unclockedCheck{}
+	defer delete(check.Store, "clocked_check")
+	defer delete(check.Store, "unclocked_check")
+
+	for _, tCase := range []struct {
+		name     string
+		executed int64
+	}{
+		{"clocked_check", 1479057736},
+		{"unclocked_check", 42},
+	} {
+		output, err := executeCheck(
+			&check.Request{
+				Definition: &check.Definition{
+					Check: &stdCheck.Check{Name: tCase.name},
+				},
+			},
+			clk,
+			nil,
+		)
+
+		if err != nil {
+			t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+		}
+
+		if output.Executed != tCase.executed {
+			t.Errorf(
+				"Expected %s execution time to be %d but got %d instead!",
+				tCase.name,
+				tCase.executed,
+				output.Executed,
+			)
+		}
+	}
+}
+
 func TestExecuteExternalCheck(t *testing.T) {
 	validateCheckOutput(
-		&stdCheck.CheckRequest{Check: &stdCheck.Check{Command: "printf Test"}},
+		&check.Request{
+			Definition: &check.Definition{
+				Check: &stdCheck.Check{Command: "printf Test"},
+			},
+		},
 		&stdCheck.CheckOutput{Status: 0, Output: "Test"},
 		t,
 	)
@@ -86,7 +138,12 @@ func TestExecuteExternalCheck(t *testing.T) {
 
 func TestExecuteEmptyCheck(t *testing.T) {
 	output, err := executeCheck(
-		&stdCheck.CheckRequest{Check: &stdCheck.Check{}, Issued: 1479057736},
+		&check.Request{
+			Definition: &check.Definition{Check: &stdCheck.Check{}},
+			Issued:     1479057736,
+		},
+		clock.Real,
+		nil,
 	)
 
 	if err != commandKeyError {
@@ -0,0 +1,154 @@
+package facts
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Provider discovers the value of a fact
+type Provider func() (interface{}, error)
+
+// Store holds the fact providers by fact name, embedding applications can
+// register their own
+var Store = map[string]Provider{
+	"hostname":   Hostname,
+	"os":         OS,
+	"kernel":     Kernel,
+	"arch":       Arch,
+	"uptime":     Uptime,
+	"pid":        PID,
+	"go_version": GoVersion,
+	"boot_time":  BootTime,
+}
+
+// Static holds the names of the facts which don't change while the client
+// runs, they are only discovered once
+var Static = map[string]bool{
+	"hostname":   true,
+	"os":         true,
+	"kernel":     true,
+	"arch":       true,
+	"go_version": true,
+	"boot_time":  true,
+}
+
+// ProcRoot is the mount point of the proc filesystem
+var ProcRoot = "/proc"
+
+var errBootTimeNotFound = errors.New("No btime entry found")
+
+func readProcFile(path ...string) (string, error) {
+	buf, err := ioutil.ReadFile(
+		filepath.Join(append([]string{ProcRoot}, path...)...),
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(buf)), nil
+}
+
+func Hostname() (interface{}, error) {
+	return os.Hostname()
+}
+
+func OS() (interface{}, error) {
+	return runtime.GOOS, nil
+}
+
+// Kernel returns the kernel release
+func Kernel() (interface{}, error) {
+	return readProcFile("sys", "kernel", "osrelease")
+}
+
+func Arch() (interface{}, error) {
+	return runtime.GOARCH, nil
+}
+
+// Uptime returns the number of seconds since the host booted
+func Uptime() (interface{}, error) {
+	v, err := readProcFile("uptime")
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(v)
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Malformed uptime: %s", v)
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return int64(uptime), nil
+}
+
+func PID() (interface{}, error) {
+	return os.Getpid(), nil
+}
+
+func GoVersion() (interface{}, error) {
+	return runtime.Version(), nil
+}
+
+// BootTime returns the boot time of the host as a unix timestamp
+func BootTime() (interface{}, error) {
+	v, err := readProcFile("stat")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(v, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "btime" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+
+	return nil, errBootTimeNotFound
+}
+
+// Discover returns the value of the given facts, the ones without provider
+// or whose provider failed are reported in the returned errors
+func Discover(
+	names []string,
+	providers map[string]Provider,
+) (map[string]interface{}, []error) {
+	facts := make(map[string]interface{}, len(names))
+	errs := []error{}
+
+	for _, name := range names {
+		provider, ok := providers[name]
+
+		if !ok {
+			errs = append(errs, fmt.Errorf("No provider for the fact %s", name))
+			continue
+		}
+
+		v, err := provider()
+
+		if err != nil {
+			errs = append(
+				errs,
+				fmt.Errorf("Failed to discover the fact %s: %s", name, err.Error()),
+			)
+			continue
+		}
+
+		facts[name] = v
+	}
+
+	return facts, errs
+}
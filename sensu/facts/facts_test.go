@@ -0,0 +1,85 @@
+package facts
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func withProcRoot(root string, fn func()) {
+	origProcRoot := ProcRoot
+	ProcRoot = root
+	defer func() { ProcRoot = origProcRoot }()
+
+	fn()
+}
+
+func TestProcFacts(t *testing.T) {
+	withProcRoot("testdata/proc", func() {
+		for _, tCase := range []struct {
+			provider Provider
+			expected interface{}
+		}{
+			{Kernel, "4.9.0-3-amd64"},
+			{Uptime, int64(350735)},
+			{BootTime, int64(1062191376)},
+		} {
+			v, err := tCase.provider()
+
+			if err != nil {
+				t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+			}
+
+			if v != tCase.expected {
+				t.Errorf("Expected %#v but got %#v instead!", tCase.expected, v)
+			}
+		}
+	})
+}
+
+func TestProcFactsUnavailable(t *testing.T) {
+	withProcRoot("testdata/missing", func() {
+		for _, provider := range []Provider{Kernel, Uptime, BootTime} {
+			if _, err := provider(); err == nil {
+				t.Error("Expected an error but got nil instead!")
+			}
+		}
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	hostname, _ := os.Hostname()
+	failure := errors.New("failure")
+
+	facts, errs := Discover(
+		[]string{"hostname", "os", "arch", "pid", "go_version", "custom", "failing", "unknown"},
+		map[string]Provider{
+			"hostname":   Hostname,
+			"os":         OS,
+			"arch":       Arch,
+			"pid":        PID,
+			"go_version": GoVersion,
+			"custom":     func() (interface{}, error) { return "foo", nil },
+			"failing":    func() (interface{}, error) { return nil, failure },
+		},
+	)
+
+	expected := map[string]interface{}{
+		"hostname":   hostname,
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"pid":        os.Getpid(),
+		"go_version": runtime.Version(),
+		"custom":     "foo",
+	}
+
+	if !reflect.DeepEqual(facts, expected) {
+		t.Errorf("Expected %#v but got %#v instead!", expected, facts)
+	}
+
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors but got %v instead!", errs)
+	}
+}
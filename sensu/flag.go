@@ -24,6 +24,7 @@ func ExtractFlags() *configFlagSet {
 
 	cmdlineParser.BoolVar(&flags.verbose, "v", false, "Verbose mode")
 	cmdlineParser.StringVar(&flags.configFile, "c", "", "Config file path")
+	cmdlineParser.StringVar(&flags.configDir, "d", "", "Config directory path")
 
 	_ = cmdlineParser.Parse(os.Args[1:])
 
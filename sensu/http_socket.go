@@ -0,0 +1,135 @@
+package sensu
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/upfluence/goutils/log"
+)
+
+const (
+	defaultHTTPSocketBind = "127.0.0.1"
+	defaultHTTPSocketPort = 3031
+)
+
+// httpSocketConfig is the client http_socket block, the socket is only
+// started when the block is defined
+type httpSocketConfig struct {
+	Bind string `json:"bind,omitempty"`
+	Port int    `json:"port,omitempty"`
+}
+
+func (c *httpSocketConfig) address() string {
+	bind, port := defaultHTTPSocketBind, defaultHTTPSocketPort
+
+	if c.Bind != "" {
+		bind = c.Bind
+	}
+
+	if c.Port != 0 {
+		port = c.Port
+	}
+
+	return net.JoinHostPort(bind, strconv.Itoa(port))
+}
+
+// applyEnv overrides the socket settings with the ones provided through
+// environment variables
+func (c *httpSocketConfig) applyEnv() error {
+	if v := os.Getenv("SENSU_CLIENT_HTTP_SOCKET_BIND"); v != "" {
+		c.Bind = v
+	}
+
+	port, ok, err := parsePositiveEnv("SENSU_CLIENT_HTTP_SOCKET_PORT")
+
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		if port > 65535 {
+			return fmt.Errorf("Invalid value for SENSU_CLIENT_HTTP_SOCKET_PORT: %d", port)
+		}
+
+		c.Port = int(port)
+	}
+
+	return nil
+}
+
+// transportInfo is implemented by the transports able to describe their
+// state, the description is exposed by the info endpoint
+type transportInfo interface {
+	Info() map[string]interface{}
+}
+
+// info returns the client state exposed by the info endpoint
+func (c *Client) info() map[string]interface{} {
+	transport := map[string]interface{}{}
+
+	if t, ok := c.Transport.(transportInfo); ok {
+		for k, v := range t.Info() {
+			transport[k] = v
+		}
+	}
+
+	transport["name"] = c.Config.TransportName()
+	transport["connected"] = c.Transport.IsConnected()
+
+	return map[string]interface{}{
+		"sensu":     map[string]interface{}{"version": Version},
+		"client":    c.Config.client().attributes(),
+		"transport": transport,
+	}
+}
+
+func (c *Client) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, err := redactJSON(c.info(), c.redactKeys())
+
+	if err != nil {
+		log.Warningf("Something went wrong: %s", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(p)
+}
+
+func (c *Client) httpSocketHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info", c.handleInfo)
+
+	return mux
+}
+
+// startHTTPSocket serves the client HTTP socket if it is configured, the
+// returned listener is nil otherwise
+func (c *Client) startHTTPSocket() (net.Listener, error) {
+	cfg := c.Config.client().HTTPSocket
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	l, err := net.Listen("tcp", cfg.address())
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Noticef("HTTP socket listening on %s", l.Addr().String())
+
+	go http.Serve(l, c.httpSocketHandler())
+
+	return l, nil
+}
@@ -0,0 +1,145 @@
+package sensu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func TestHTTPSocketAddress(t *testing.T) {
+	for _, tCase := range []struct {
+		cfg      *httpSocketConfig
+		expected string
+	}{
+		{&httpSocketConfig{}, "127.0.0.1:3031"},
+		{&httpSocketConfig{Bind: "0.0.0.0", Port: 4242}, "0.0.0.0:4242"},
+	} {
+		if address := tCase.cfg.address(); address != tCase.expected {
+			t.Errorf(
+				"Expected address to be %s but got %s instead!",
+				tCase.expected,
+				address,
+			)
+		}
+	}
+}
+
+func TestHTTPSocketFromEnvVars(t *testing.T) {
+	for _, tCase := range []struct {
+		env      map[string]string
+		expected *httpSocketConfig
+		valid    bool
+	}{
+		{map[string]string{}, nil, true},
+		{
+			map[string]string{"SENSU_CLIENT_HTTP_SOCKET_PORT": "4242"},
+			&httpSocketConfig{Port: 4242},
+			true,
+		},
+		{
+			map[string]string{
+				"SENSU_CLIENT_HTTP_SOCKET_BIND": "0.0.0.0",
+				"SENSU_CLIENT_HTTP_SOCKET_PORT": "3030",
+			},
+			&httpSocketConfig{Bind: "0.0.0.0", Port: 3030},
+			true,
+		},
+		{map[string]string{"SENSU_CLIENT_HTTP_SOCKET_PORT": "foo"}, nil, false},
+		{map[string]string{"SENSU_CLIENT_HTTP_SOCKET_PORT": "70000"}, nil, false},
+	} {
+		for k, v := range tCase.env {
+			os.Setenv(k, v)
+		}
+
+		cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+		for k := range tCase.env {
+			os.Unsetenv(k)
+		}
+
+		if !tCase.valid {
+			if err == nil {
+				t.Errorf("Expected an error for %v but got nil instead!", tCase.env)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		if s := cfg.client().HTTPSocket; !reflect.DeepEqual(s, tCase.expected) {
+			t.Errorf("Expected the http socket to be %+v but got %+v instead!", tCase.expected, s)
+		}
+	}
+}
+
+func TestInfoEndpoint(t *testing.T) {
+	transport := memory.NewTransport()
+	transport.Connect()
+
+	client := NewClient(
+		transport,
+		&Config{
+			config: &configPayload{
+				Client:    &clientConfig{Client: &stdClient.Client{Name: "foo"}},
+				Transport: &transportConfig{Name: "memory"},
+			},
+		},
+	)
+
+	server := httptest.NewServer(client.httpSocketHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/info")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		Sensu struct {
+			Version string `json:"version"`
+		} `json:"sensu"`
+		Transport struct {
+			Name      string `json:"name"`
+			Connected bool   `json:"connected"`
+		} `json:"transport"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if info.Sensu.Version != Version {
+		t.Errorf("Expected version to be %s but got %s instead!", Version, info.Sensu.Version)
+	}
+
+	if info.Transport.Name != "memory" || !info.Transport.Connected {
+		t.Errorf(
+			"Expected a connected memory transport but got %+v instead!",
+			info.Transport,
+		)
+	}
+
+	resp, err = http.Post(server.URL+"/info", "application/json", nil)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status to be 405 but got %d instead!", resp.StatusCode)
+	}
+}
@@ -0,0 +1,181 @@
+package sensu
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type undefinedVariablesError []string
+
+func (e undefinedVariablesError) Error() string {
+	return fmt.Sprintf(
+		"Undefined environment variables in config: %s",
+		strings.Join(e, ", "),
+	)
+}
+
+// interpolateEnv expands the ${VAR} and ${VAR:-default} references of a
+// config value, the default is used when VAR is unset or empty. $$ is the
+// escape sequence for a literal $, any other $ is kept as is. The variables
+// without a value nor a default are appended to undefined.
+func interpolateEnv(s string, undefined *[]string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var buf bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+
+			if end < 0 {
+				return "", fmt.Errorf("Unterminated variable reference in \"%s\"", s)
+			}
+
+			expr := s[i+2 : i+2+end]
+			name, def, hasDefault := expr, "", false
+
+			if idx := strings.Index(expr, ":-"); idx >= 0 {
+				name, def, hasDefault = expr[:idx], expr[idx+2:], true
+			}
+
+			if name == "" {
+				return "", fmt.Errorf("Empty variable reference in \"%s\"", s)
+			}
+
+			if v := os.Getenv(name); v != "" {
+				buf.WriteString(v)
+			} else if hasDefault {
+				buf.WriteString(def)
+			} else if _, ok := os.LookupEnv(name); !ok {
+				*undefined = append(*undefined, name)
+			}
+
+			i += end + 2
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+
+	return buf.String(), nil
+}
+
+func interpolateConfigValue(v interface{}, undefined *[]string) (interface{}, error) {
+	switch vv := v.(type) {
+	case string:
+		return interpolateEnv(vv, undefined)
+	case map[string]interface{}:
+		for k, e := range vv {
+			r, err := interpolateConfigValue(e, undefined)
+
+			if err != nil {
+				return nil, err
+			}
+
+			vv[k] = r
+		}
+	case []interface{}:
+		for i, e := range vv {
+			r, err := interpolateConfigValue(e, undefined)
+
+			if err != nil {
+				return nil, err
+			}
+
+			vv[i] = r
+		}
+	}
+
+	return v, nil
+}
+
+// interpolateChecks expands the references of the check definitions but
+// their command, the shell of the check expands its variables at runtime
+func interpolateChecks(v interface{}, undefined *[]string) error {
+	checks, ok := v.([]interface{})
+
+	if !ok {
+		_, err := interpolateConfigValue(v, undefined)
+		return err
+	}
+
+	for _, c := range checks {
+		definition, ok := c.(map[string]interface{})
+
+		if !ok {
+			if _, err := interpolateConfigValue(c, undefined); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for k, e := range definition {
+			if k == "command" {
+				continue
+			}
+
+			r, err := interpolateConfigValue(e, undefined)
+
+			if err != nil {
+				return err
+			}
+
+			definition[k] = r
+		}
+	}
+
+	return nil
+}
+
+// interpolateConfig expands the environment variable references of every
+// string value of a decoded config but the check commands, in place
+func interpolateConfig(m map[string]interface{}) error {
+	undefined := []string{}
+
+	for k, v := range m {
+		if k == "checks" {
+			if err := interpolateChecks(v, &undefined); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		r, err := interpolateConfigValue(v, &undefined)
+
+		if err != nil {
+			return err
+		}
+
+		m[k] = r
+	}
+
+	if len(undefined) == 0 {
+		return nil
+	}
+
+	sort.Strings(undefined)
+	names := undefinedVariablesError{}
+
+	for i, name := range undefined {
+		if i == 0 || undefined[i-1] != name {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/upfluence/goutils/log"
-	"github.com/upfluence/sensu-go/sensu/client"
+	"github.com/upfluence/sensu-client-go/sensu/facts"
 )
 
 const defaultInterval = 20 * time.Second
@@ -14,16 +14,45 @@ const defaultInterval = 20 * time.Second
 type KeepAlive struct {
 	Client    *Client
 	closeChan chan bool
+
+	// The static facts already discovered and the facts whose provider
+	// failed, which are only logged once
+	static map[string]interface{}
+	failed map[string]bool
 }
 
 type keepAlivePayload struct {
-	*client.Client
-	Timestamp int64  `json:"timestamp"`
-	Version   string `json:"version"`
+	*clientConfig
+	Timestamp int64                  `json:"timestamp"`
+	Version   string                 `json:"version"`
+	Build     *buildInfo             `json:"build,omitempty"`
+	Facts     map[string]interface{} `json:"facts,omitempty"`
+}
+
+func (p keepAlivePayload) MarshalJSON() ([]byte, error) {
+	m := p.clientConfig.attributes()
+
+	m["timestamp"] = p.Timestamp
+	m["version"] = p.Version
+
+	if p.Build != nil {
+		m["build"] = p.Build
+	}
+
+	if len(p.Facts) > 0 {
+		m["facts"] = p.Facts
+	}
+
+	return redactJSON(m, p.clientConfig.redactKeys())
 }
 
 func NewKeepAlive(c *Client) *KeepAlive {
-	return &KeepAlive{c, make(chan bool)}
+	return &KeepAlive{
+		Client:    c,
+		closeChan: make(chan bool),
+		static:    make(map[string]interface{}),
+		failed:    make(map[string]bool),
+	}
 }
 
 func (k *KeepAlive) publishKeepAlive() {
@@ -31,12 +60,18 @@ func (k *KeepAlive) publishKeepAlive() {
 
 	p, err := json.Marshal(
 		keepAlivePayload{
-			k.Client.Config.Client(),
-			time.Now().Unix(),
-			currentVersion,
+			k.Client.Config.client(),
+			k.Client.clock().Now().Unix(),
+			Version,
+			build(),
+			k.facts(),
 		},
 	)
 
+	if err == nil {
+		p, err = k.Client.sign(p)
+	}
+
 	if err != nil {
 		log.Warningf("Something went wrong: %s", err.Error())
 		return
@@ -50,14 +85,70 @@ func (k *KeepAlive) publishKeepAlive() {
 	}
 }
 
+// facts discovers the facts enabled in the client configuration, the static
+// ones are only discovered until their provider succeeds
+func (k *KeepAlive) facts() map[string]interface{} {
+	names := k.Client.Config.client().Facts
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	providers := map[string]facts.Provider{"checks": k.checksFact}
+
+	for name, provider := range facts.Store {
+		providers[name] = provider
+	}
+
+	values := make(map[string]interface{}, len(names))
+
+	for _, name := range names {
+		if v, ok := k.static[name]; ok {
+			values[name] = v
+			continue
+		}
+
+		discovered, errs := facts.Discover([]string{name}, providers)
+
+		if len(errs) > 0 {
+			if !k.failed[name] {
+				log.Warningf("Something went wrong: %s", errs[0].Error())
+				k.failed[name] = true
+			}
+
+			continue
+		}
+
+		values[name] = discovered[name]
+
+		if facts.Static[name] {
+			k.static[name] = discovered[name]
+		}
+	}
+
+	return values
+}
+
+// checksFact returns the names of the checks defined in the configuration
+func (k *KeepAlive) checksFact() (interface{}, error) {
+	names := []string{}
+
+	for _, check := range k.Client.Config.Checks() {
+		names = append(names, check.Name)
+	}
+
+	return names, nil
+}
+
 func (k *KeepAlive) Start() error {
-	t := time.Tick(defaultInterval)
+	t := k.Client.clock().NewTicker(k.Client.Config.keepAliveInterval())
+	defer t.Stop()
 
 	k.publishKeepAlive()
 
 	for {
 		select {
-		case <-t:
+		case <-t.C():
 			k.publishKeepAlive()
 		case <-k.closeChan:
 			return nil
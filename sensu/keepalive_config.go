@@ -0,0 +1,132 @@
+package sensu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var errInvalidKeepAliveThresholds = errors.New(
+	"The keepalive warning threshold must be lower than the critical one",
+)
+
+type keepAliveThresholds struct {
+	Warning  int64 `json:"warning,omitempty"`
+	Critical int64 `json:"critical,omitempty"`
+}
+
+// keepAliveConfig is the client keepalive block, sent along with every
+// keepalive so the server evaluates the staleness of this client with its
+// own thresholds and handlers
+type keepAliveConfig struct {
+	// Seconds between two keepalives, not a ruby client attribute
+	Interval   int64                `json:"interval,omitempty"`
+	Thresholds *keepAliveThresholds `json:"thresholds,omitempty"`
+	Handler    string               `json:"handler,omitempty"`
+	Handlers   []string             `json:"handlers,omitempty"`
+	Refresh    int64                `json:"refresh,omitempty"`
+}
+
+func (k *keepAliveConfig) interval() time.Duration {
+	if k != nil && k.Interval > 0 {
+		return time.Duration(k.Interval) * time.Second
+	}
+
+	return defaultInterval
+}
+
+func (k *keepAliveConfig) validate() error {
+	if k == nil {
+		return nil
+	}
+
+	for name, v := range map[string]int64{
+		"interval": k.Interval,
+		"refresh":  k.Refresh,
+	} {
+		if v < 0 {
+			return fmt.Errorf("Invalid keepalive %s: %d", name, v)
+		}
+	}
+
+	if t := k.Thresholds; t != nil {
+		if t.Warning < 0 || t.Critical < 0 {
+			return fmt.Errorf(
+				"Invalid keepalive thresholds: warning %d, critical %d",
+				t.Warning,
+				t.Critical,
+			)
+		}
+
+		if t.Warning > 0 && t.Critical > 0 && t.Warning >= t.Critical {
+			return errInvalidKeepAliveThresholds
+		}
+
+		if t.Warning > 0 && k.interval() >= time.Duration(t.Warning)*time.Second {
+			return fmt.Errorf(
+				"The keepalive interval (%s) must be lower than the warning threshold (%ds)",
+				k.interval(),
+				t.Warning,
+			)
+		}
+	}
+
+	return nil
+}
+
+func parsePositiveEnv(env string) (int64, bool, error) {
+	v := os.Getenv(env)
+
+	if v == "" {
+		return 0, false, nil
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+
+	if err != nil || i <= 0 {
+		return 0, false, fmt.Errorf("Invalid value for %s: %s", env, v)
+	}
+
+	return i, true, nil
+}
+
+// applyEnv overrides the keepalive settings with the ones provided through
+// environment variables
+func (k *keepAliveConfig) applyEnv() error {
+	for env, field := range map[string]*int64{
+		"SENSU_CLIENT_KEEPALIVE_INTERVAL": &k.Interval,
+		"SENSU_CLIENT_KEEPALIVE_REFRESH":  &k.Refresh,
+	} {
+		if v, ok, err := parsePositiveEnv(env); err != nil {
+			return err
+		} else if ok {
+			*field = v
+		}
+	}
+
+	thresholds := &keepAliveThresholds{}
+
+	if k.Thresholds != nil {
+		thresholds = k.Thresholds
+	}
+
+	for env, field := range map[string]*int64{
+		"SENSU_CLIENT_KEEPALIVE_WARNING":  &thresholds.Warning,
+		"SENSU_CLIENT_KEEPALIVE_CRITICAL": &thresholds.Critical,
+	} {
+		if v, ok, err := parsePositiveEnv(env); err != nil {
+			return err
+		} else if ok {
+			*field = v
+			k.Thresholds = thresholds
+		}
+	}
+
+	if v := os.Getenv("SENSU_CLIENT_KEEPALIVE_HANDLERS"); v != "" {
+		k.Handlers = split(v, ",")
+	}
+
+	return nil
+}
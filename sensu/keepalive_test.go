@@ -0,0 +1,248 @@
+package sensu
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/facts"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func TestKeepAliveConfig(t *testing.T) {
+	cfg, err := NewConfigFromFile(nil, "testdata/client-keepalive.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if interval := cfg.keepAliveInterval(); interval != 10*time.Second {
+		t.Errorf("Expected keepalive interval to be 10s but got %s instead!", interval)
+	}
+
+	transport := newTestTransport()
+
+	NewKeepAlive(&Client{Transport: transport, Config: cfg}).publishKeepAlive()
+
+	if lastMessage(transport) == nil {
+		t.Fatal("Expected a keepalive to be published but got nothing!")
+	}
+
+	var payload struct {
+		Name      string          `json:"name"`
+		KeepAlive keepAliveConfig `json:"keepalive"`
+	}
+
+	if err := json.Unmarshal(lastMessage(transport).Body, &payload); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := keepAliveConfig{
+		Interval:   10,
+		Thresholds: &keepAliveThresholds{Warning: 40, Critical: 60},
+		Handlers:   []string{"pagerduty"},
+		Refresh:    1800,
+	}
+
+	if !reflect.DeepEqual(payload.KeepAlive, expected) {
+		t.Errorf(
+			"Expected keepalive block to be %+v but got %+v instead!",
+			expected,
+			payload.KeepAlive,
+		)
+	}
+}
+
+func TestKeepAliveConfigFromEnvVars(t *testing.T) {
+	for k, v := range map[string]string{
+		"SENSU_CLIENT_KEEPALIVE_WARNING":  "30",
+		"SENSU_CLIENT_KEEPALIVE_CRITICAL": "45",
+		"SENSU_CLIENT_KEEPALIVE_HANDLERS": "email,slack",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-keepalive.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := &keepAliveConfig{
+		Interval:   10,
+		Thresholds: &keepAliveThresholds{Warning: 30, Critical: 45},
+		Handlers:   []string{"email", "slack"},
+		Refresh:    1800,
+	}
+
+	if k := cfg.client().KeepAlive; !reflect.DeepEqual(k, expected) {
+		t.Errorf("Expected keepalive block to be %+v but got %+v instead!", expected, k)
+	}
+}
+
+func TestKeepAliveConfigValidation(t *testing.T) {
+	for _, tCase := range []struct {
+		config *keepAliveConfig
+		valid  bool
+	}{
+		{nil, true},
+		{&keepAliveConfig{}, true},
+		{&keepAliveConfig{Interval: -1}, false},
+		{&keepAliveConfig{Refresh: -1}, false},
+		{
+			&keepAliveConfig{Thresholds: &keepAliveThresholds{Critical: 30}},
+			true,
+		},
+		{
+			&keepAliveConfig{
+				Thresholds: &keepAliveThresholds{Warning: 30, Critical: 60},
+			},
+			true,
+		},
+		{
+			&keepAliveConfig{
+				Thresholds: &keepAliveThresholds{Warning: 60, Critical: 60},
+			},
+			false,
+		},
+		{
+			&keepAliveConfig{Thresholds: &keepAliveThresholds{Warning: -1}},
+			false,
+		},
+		{
+			&keepAliveConfig{Thresholds: &keepAliveThresholds{Warning: 15}},
+			false,
+		},
+		{
+			&keepAliveConfig{
+				Interval:   5,
+				Thresholds: &keepAliveThresholds{Warning: 15},
+			},
+			true,
+		},
+	} {
+		if err := tCase.config.validate(); (err == nil) != tCase.valid {
+			t.Errorf(
+				"Expected validity of %+v to be %t but got \"%v\" instead!",
+				tCase.config,
+				tCase.valid,
+				err,
+			)
+		}
+	}
+}
+
+func TestKeepAliveFacts(t *testing.T) {
+	facts.Store["custom"] = func() (interface{}, error) { return "foo", nil }
+	defer delete(facts.Store, "custom")
+
+	transport := newTestTransport()
+	cfg := &Config{
+		config: &configPayload{
+			Client: &clientConfig{
+				Client: &stdClient.Client{Name: "Test"},
+				Facts:  []string{"os", "checks", "custom", "unknown"},
+			},
+			Checks: []*check.Definition{
+				{Check: &stdCheck.Check{Name: "check-1"}},
+				{Check: &stdCheck.Check{Name: "check-2"}},
+			},
+		},
+	}
+
+	NewKeepAlive(&Client{Transport: transport, Config: cfg}).publishKeepAlive()
+
+	var payload struct {
+		Facts map[string]interface{} `json:"facts"`
+	}
+
+	if err := json.Unmarshal(lastMessage(transport).Body, &payload); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := map[string]interface{}{
+		"os":     runtime.GOOS,
+		"checks": []interface{}{"check-1", "check-2"},
+		"custom": "foo",
+	}
+
+	if !reflect.DeepEqual(payload.Facts, expected) {
+		t.Errorf("Expected facts to be %#v but got %#v instead!", expected, payload.Facts)
+	}
+}
+
+func TestKeepAliveStaticFacts(t *testing.T) {
+	calls := map[string]int{}
+
+	for _, name := range []string{"static", "dynamic", "failing"} {
+		name := name
+
+		facts.Store[name] = func() (interface{}, error) {
+			calls[name]++
+
+			if name == "failing" {
+				return nil, errors.New("unavailable")
+			}
+
+			return calls[name], nil
+		}
+		defer delete(facts.Store, name)
+	}
+
+	facts.Static["static"] = true
+	defer delete(facts.Static, "static")
+
+	k := NewKeepAlive(
+		newTestClient(
+			&configPayload{
+				Client: &clientConfig{
+					Client: &stdClient.Client{Name: "Test"},
+					Facts:  []string{"static", "dynamic", "failing"},
+				},
+			},
+		),
+	)
+
+	k.facts()
+	values := k.facts()
+	expected := map[string]interface{}{"static": 1, "dynamic": 2}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected facts to be %#v but got %#v instead!", expected, values)
+	}
+
+	if calls["failing"] != 2 || !k.failed["failing"] {
+		t.Errorf(
+			"Expected the failing fact to be retried and marked as failed but got %d calls instead!",
+			calls["failing"],
+		)
+	}
+}
+
+func TestKeepAliveWithoutFacts(t *testing.T) {
+	transport := newTestTransport()
+	cfg := &Config{
+		config: &configPayload{
+			Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+		},
+	}
+
+	NewKeepAlive(&Client{Transport: transport, Config: cfg}).publishKeepAlive()
+
+	var payload map[string]interface{}
+
+	if err := json.Unmarshal(lastMessage(transport).Body, &payload); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if v, ok := payload["facts"]; ok {
+		t.Errorf("Expected no facts but got %#v instead!", v)
+	}
+}
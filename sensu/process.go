@@ -0,0 +1,175 @@
+package sensu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+)
+
+const (
+	capSetGID = 6
+	capSetUID = 7
+)
+
+var errCantSwitchUser = errors.New(
+	"The client needs to run as root or with the CAP_SETUID and CAP_SETGID capabilities",
+)
+
+// canSwitchUser reports whether the client is allowed to run the checks as
+// another user, it is replaced in the tests
+var canSwitchUser = func() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+
+	caps, err := effectiveCapabilities()
+
+	if err != nil {
+		return errCantSwitchUser
+	}
+
+	if caps&(1<<capSetUID) == 0 || caps&(1<<capSetGID) == 0 {
+		return errCantSwitchUser
+	}
+
+	return nil
+}
+
+// effectiveCapabilities reads the CapEff mask of the client process
+func effectiveCapabilities() (uint64, error) {
+	f, err := os.Open("/proc/self/status")
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), "CapEff:"); v != scanner.Text() {
+			return strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+		}
+	}
+
+	return 0, errors.New("No effective capabilities found")
+}
+
+func (c *Config) process() *check.Process {
+	if c.config == nil {
+		c.config = &configPayload{}
+	}
+
+	if c.config.Process == nil {
+		c.config.Process = &check.Process{}
+	}
+
+	return c.config.Process
+}
+
+// checkProcess returns the process settings of a check, the ones of its
+// local definition override the global ones. The check requests received
+// through the subscriptions can't set them.
+func (c *Config) checkProcess(definition *check.Definition) check.Process {
+	var process check.Process
+
+	if c != nil && c.config != nil && c.config.Process != nil {
+		process = *c.config.Process
+	}
+
+	if definition == nil {
+		return process
+	}
+
+	return process.Merge(definition.Process)
+}
+
+func (c *Client) checkProcess(definition *check.Definition) check.Process {
+	var cfg *Config
+
+	if c != nil {
+		cfg = c.Config
+	}
+
+	return cfg.checkProcess(definition)
+}
+
+func (c *Config) applyProcessEnv() error {
+	if v := os.Getenv("SENSU_CHECKS_USER"); v != "" {
+		c.process().User = v
+	}
+
+	if v := os.Getenv("SENSU_CHECKS_GROUP"); v != "" {
+		c.process().Group = v
+	}
+
+	if v, ok := os.LookupEnv("SENSU_CHECKS_GROUPS"); ok {
+		c.process().Groups = split(v, ",")
+	}
+
+	if v := os.Getenv("SENSU_CHECKS_CWD"); v != "" {
+		c.process().Cwd = v
+	}
+
+	if v := os.Getenv("SENSU_CHECKS_PLUGIN_PATHS"); v != "" {
+		c.process().PluginPaths = split(v, ",")
+	}
+
+	if v := os.Getenv("SENSU_CHECKS_CLEAN_ENV"); v != "" {
+		clean, err := strconv.ParseBool(v)
+
+		if err != nil {
+			return fmt.Errorf("Invalid value for SENSU_CHECKS_CLEAN_ENV: %s", v)
+		}
+
+		c.process().CleanEnv = clean
+	}
+
+	return nil
+}
+
+// validateProcess checks the limits and resolves the users and the groups
+// of the checks, so the client refuses to start if it can't run them as
+// configured
+func (c *Config) validateProcess() error {
+	definitions := append([]*check.Definition{nil}, c.CheckDefinitions()...)
+
+	for _, definition := range definitions {
+		var (
+			process    = c.checkProcess(definition)
+			credential *syscall.Credential
+			err        = process.Validate()
+		)
+
+		if err == nil {
+			credential, err = process.Credential()
+		}
+
+		if err == nil && credential != nil {
+			err = canSwitchUser()
+		}
+
+		if err == nil {
+			continue
+		}
+
+		if definition == nil {
+			return fmt.Errorf("The client can't run the checks as configured: %s", err.Error())
+		}
+
+		return fmt.Errorf(
+			"The client can't run the check %s as configured: %s",
+			definition.Name,
+			err.Error(),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package sensu
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func TestValidateProcess(t *testing.T) {
+	defer func(fn func() error) { canSwitchUser = fn }(canSwitchUser)
+
+	for _, tCase := range []struct {
+		config        *Config
+		canSwitchUser error
+		err           string
+	}{
+		{newTestConfig(&configPayload{}), errCantSwitchUser, ""},
+		{newTestConfig(&configPayload{Process: &check.Process{User: "nobody"}}), nil, ""},
+		{
+			newTestConfig(&configPayload{Process: &check.Process{User: "nobody"}}),
+			errCantSwitchUser,
+			"The client can't run the checks as configured: " + errCantSwitchUser.Error(),
+		},
+		{
+			newTestConfig(&configPayload{
+				Checks: []*check.Definition{
+					{
+						Check:   &stdCheck.Check{Name: "disk"},
+						Process: check.Process{Group: "nosuchgroup"},
+					},
+				},
+			}),
+			nil,
+			"The client can't run the check disk as configured: Unknown group: nosuchgroup",
+		},
+		{
+			newTestConfig(&configPayload{
+				Process: &check.Process{Nice: 10, CPUTime: 60},
+				Checks: []*check.Definition{
+					{
+						Check:   &stdCheck.Check{Name: "disk"},
+						Process: check.Process{Nice: -21},
+					},
+				},
+			}),
+			nil,
+			"The client can't run the check disk as configured: Invalid nice value: -21",
+		},
+	} {
+		canSwitchUser = func() error { return tCase.canSwitchUser }
+
+		err := tCase.config.validateProcess()
+
+		if tCase.err == "" && err != nil {
+			t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+		} else if tCase.err != "" && (err == nil || err.Error() != tCase.err) {
+			t.Errorf("Expected error \"%s\" but got \"%v\" instead!", tCase.err, err)
+		}
+	}
+}
+
+func TestProcessFromEnvVars(t *testing.T) {
+	defer func(fn func() error) { canSwitchUser = fn }(canSwitchUser)
+	canSwitchUser = func() error { return nil }
+
+	for k, v := range map[string]string{
+		"SENSU_CHECKS_USER":   "nobody",
+		"SENSU_CHECKS_GROUP":  "nogroup",
+		"SENSU_CHECKS_GROUPS": "",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := check.Process{User: "nobody", Group: "nogroup", Groups: []string{}}
+
+	if p := cfg.checkProcess(nil); !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected the process settings to be %+v but got %+v instead!", expected, p)
+	}
+
+	canSwitchUser = func() error { return errors.New("Not permitted") }
+
+	if _, err := NewConfigFromFile(nil, "testdata/client-attributes.json"); err == nil {
+		t.Error("Expected an error without the permission to switch user but got nil instead!")
+	}
+}
+
+func TestHandleMessageProcess(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Switching user requires root")
+	}
+
+	for _, tCase := range []struct {
+		request string
+		output  string
+	}{
+		{`{"name":"remote","command":"id -u","user":"root","issued":1479057736}`, "65534\n"},
+		{`{"name":"local","user":"root","issued":1479057736}`, "0\n"},
+	} {
+		transport := newTestTransport()
+		cfg := newTestConfig(&configPayload{
+			Process: &check.Process{User: "nobody"},
+			Checks: []*check.Definition{
+				{
+					Check:   &stdCheck.Check{Name: "local", Command: "id -u"},
+					Process: check.Process{User: "0"},
+				},
+			},
+		})
+
+		NewSubscriber(
+			"test",
+			&Client{Config: cfg, Transport: transport},
+		).handleMessage([]byte(tCase.request))
+
+		if lastMessage(transport) == nil {
+			t.Fatal("Expected a check result to be published but got nothing!")
+		}
+
+		var response CheckResponse
+
+		if err := json.Unmarshal(lastMessage(transport).Body, &response); err != nil {
+			t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+		}
+
+		if response.Check.Output != tCase.output {
+			t.Errorf(
+				"Expected output \"%s\" for %s but got \"%s\" instead!",
+				tCase.output,
+				tCase.request,
+				response.Check.Output,
+			)
+		}
+
+		if strings.Contains(string(lastMessage(transport).Body), `"user"`) {
+			t.Errorf("Expected the result not to contain the user but got %s instead!", lastMessage(transport).Body)
+		}
+	}
+}
+
+func TestProcessEnvironFromEnvVars(t *testing.T) {
+	for k, v := range map[string]string{
+		"SENSU_CHECKS_CWD":          "/",
+		"SENSU_CHECKS_PLUGIN_PATHS": "/etc/sensu/plugins,/opt/plugins",
+		"SENSU_CHECKS_CLEAN_ENV":    "true",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := check.Process{
+		Cwd:         "/",
+		PluginPaths: []string{"/etc/sensu/plugins", "/opt/plugins"},
+		CleanEnv:    true,
+	}
+
+	if p := cfg.checkProcess(nil); !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected the process settings to be %+v but got %+v instead!", expected, p)
+	}
+
+	os.Setenv("SENSU_CHECKS_CLEAN_ENV", "maybe")
+
+	if _, err := NewConfigFromFile(nil, "testdata/client-attributes.json"); err == nil {
+		t.Error("Expected an error for an invalid clean env flag but got nil instead!")
+	}
+}
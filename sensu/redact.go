@@ -0,0 +1,112 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const redactedValue = "REDACTED"
+
+// defaultRedactKeys are the ruby client ones
+var defaultRedactKeys = []string{
+	"password",
+	"passwd",
+	"pass",
+	"api_key",
+	"api_token",
+	"access_key",
+	"secret_key",
+	"private_key",
+	"secret",
+}
+
+// redactKeys returns the keys whose values are masked in the keepalives,
+// the logs and the info endpoint, the client redact attribute replaces the
+// default ones
+func (c *clientConfig) redactKeys() map[string]bool {
+	keys := defaultRedactKeys
+
+	if c != nil && c.Redact != nil {
+		keys = c.Redact
+	}
+
+	set := make(map[string]bool, len(keys))
+
+	for _, k := range keys {
+		set[k] = true
+	}
+
+	return set
+}
+
+// redact returns a copy of the value with the values of the given keys
+// masked, at any depth of the maps and the arrays
+func redact(v interface{}, keys map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+
+		for k, value := range v {
+			if keys[k] {
+				m[k] = redactedValue
+			} else {
+				m[k] = redact(value, keys)
+			}
+		}
+
+		return m
+	case []interface{}:
+		values := make([]interface{}, len(v))
+
+		for i, value := range v {
+			values[i] = redact(value, keys)
+		}
+
+		return values
+	}
+
+	return v
+}
+
+// redactJSON returns the JSON encoding of v with the values of the given
+// keys masked
+func redactJSON(v interface{}, keys map[string]bool) ([]byte, error) {
+	buf, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+
+	var decoded interface{}
+
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(redact(decoded, keys))
+}
+
+func (c *Client) redactKeys() map[string]bool {
+	var cfg *clientConfig
+
+	if c != nil && c.Config != nil {
+		cfg = c.Config.client()
+	}
+
+	return cfg.redactKeys()
+}
+
+// redactPayload masks the sensitive values of a JSON payload before it is
+// logged, the payloads which can't be decoded are not logged
+func (c *Client) redactPayload(payload []byte) string {
+	p, err := redactJSON(json.RawMessage(payload), c.redactKeys())
+
+	if err != nil {
+		return redactedValue
+	}
+
+	return string(p)
+}
@@ -0,0 +1,171 @@
+package sensu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/upfluence/goutils/testing/utils"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func newRedactClientConfig(redact []string) *clientConfig {
+	return &clientConfig{
+		Client: &stdClient.Client{Name: "Test"},
+		Redact: redact,
+		Attributes: map[string]interface{}{
+			"api_key": "1234",
+			"github": map[string]interface{}{
+				"user":     "sensu",
+				"password": "secret",
+				"tokens":   []interface{}{map[string]interface{}{"secret": "5678"}},
+			},
+		},
+	}
+}
+
+func TestRedact(t *testing.T) {
+	attributes := map[string]interface{}{
+		"name": "foo",
+		"pass": "1234",
+		"nested": map[string]interface{}{
+			"secret": map[string]interface{}{"id": 1},
+			"list":   []interface{}{map[string]interface{}{"passwd": "5678"}, "password"},
+		},
+	}
+
+	redacted := redact(attributes, (&clientConfig{}).redactKeys())
+
+	expected := map[string]interface{}{
+		"name": "foo",
+		"pass": redactedValue,
+		"nested": map[string]interface{}{
+			"secret": redactedValue,
+			"list":   []interface{}{map[string]interface{}{"passwd": redactedValue}, "password"},
+		},
+	}
+
+	if !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("Expected %v but got %v instead!", expected, redacted)
+	}
+
+	if attributes["pass"] != "1234" {
+		t.Errorf("Expected the original value to be kept but got %v instead!", attributes["pass"])
+	}
+}
+
+func TestRedactKeepAlive(t *testing.T) {
+	for _, tCase := range []struct {
+		redact   []string
+		expected string
+	}{
+		{
+			nil,
+			`{"api_key":"REDACTED","github":{"password":"REDACTED","tokens":[{"secret":"REDACTED"}],"user":"sensu"}}`,
+		},
+		{
+			[]string{"user"},
+			`{"api_key":"1234","github":{"password":"secret","tokens":[{"secret":"5678"}],"user":"REDACTED"}}`,
+		},
+	} {
+		c := newTestClient(&configPayload{Client: newRedactClientConfig(tCase.redact)})
+
+		NewKeepAlive(c).publishKeepAlive()
+
+		var payload struct {
+			APIKey interface{} `json:"api_key"`
+			GitHub interface{} `json:"github"`
+		}
+
+		body := lastMessage(c.Transport.(*memory.Transport)).Body
+
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		attributes, err := json.Marshal(
+			map[string]interface{}{"api_key": payload.APIKey, "github": payload.GitHub},
+		)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(string(attributes), tCase.expected, "attributes", t)
+
+		// The configuration keeps the real values
+		if v := c.Config.client().Attributes["api_key"]; v != "1234" {
+			t.Errorf("Expected the api key to be kept but got %v instead!", v)
+		}
+	}
+}
+
+func TestRedactPayload(t *testing.T) {
+	c := newTestClient(&configPayload{Client: newRedactClientConfig(nil)})
+
+	utils.ValidateStringParameter(
+		c.redactPayload([]byte(`{"check":{"name":"foo","secret":"1234","issued":1479057736}}`)),
+		`{"check":{"issued":1479057736,"name":"foo","secret":"REDACTED"}}`,
+		"payload",
+		t,
+	)
+
+	utils.ValidateStringParameter(c.redactPayload([]byte(`{`)), redactedValue, "payload", t)
+
+	utils.ValidateStringParameter(
+		(*Client)(nil).redactPayload([]byte(`{"password":"1234"}`)),
+		`{"password":"REDACTED"}`,
+		"payload",
+		t,
+	)
+}
+
+func TestRedactFromEnvVar(t *testing.T) {
+	os.Setenv("SENSU_CLIENT_REDACT", "token,password")
+	defer os.Unsetenv("SENSU_CLIENT_REDACT")
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if keys := cfg.client().redactKeys(); !reflect.DeepEqual(
+		keys,
+		map[string]bool{"token": true, "password": true},
+	) {
+		t.Errorf("Expected the redact keys to be token and password but got %v instead!", keys)
+	}
+}
+
+func TestRedactInfoEndpoint(t *testing.T) {
+	c := newTestClient(&configPayload{Client: newRedactClientConfig(nil)})
+	server := httptest.NewServer(c.httpSocketHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/info")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer resp.Body.Close()
+
+	var info struct {
+		Client struct {
+			Name   string `json:"name"`
+			APIKey string `json:"api_key"`
+		} `json:"client"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(info.Client.Name, "Test", "client name", t)
+	utils.ValidateStringParameter(info.Client.APIKey, redactedValue, "api key", t)
+}
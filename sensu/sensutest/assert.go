@@ -0,0 +1,68 @@
+package sensutest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/upfluence/sensu-client-go/sensu"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+// AssertStatus fails the test unless the result has the given status
+func AssertStatus(t testing.TB, r sensu.CheckResponse, status stdCheck.ExitStatus) {
+	if r.Check.Status != status {
+		t.Errorf(
+			"Expected status to be %d but got %d instead! (output: %q)",
+			status,
+			r.Check.Status,
+			r.Check.Output,
+		)
+	}
+}
+
+// AssertOutput fails the test unless the result has the given output, the
+// trailing new lines are ignored
+func AssertOutput(t testing.TB, r sensu.CheckResponse, output string) {
+	if actual := strings.TrimRight(r.Check.Output, "\n"); actual != strings.TrimRight(output, "\n") {
+		t.Errorf("Expected output to be %q but got %q instead!", output, r.Check.Output)
+	}
+}
+
+// AssertOutputMatches fails the test unless the output of the result
+// matches the regular expression
+func AssertOutputMatches(t testing.TB, r sensu.CheckResponse, pattern string) {
+	if !regexp.MustCompile(pattern).MatchString(r.Check.Output) {
+		t.Errorf("Expected output to match %q but got %q instead!", pattern, r.Check.Output)
+	}
+}
+
+// ExpectResult waits for the next result of the check and fails the test
+// unless it has the given status and output
+func (h *Harness) ExpectResult(
+	name string,
+	status stdCheck.ExitStatus,
+	output string,
+) sensu.CheckResponse {
+	r := h.NextResult(name)
+
+	AssertStatus(h.t, r, status)
+	AssertOutput(h.t, r, output)
+
+	return r
+}
+
+// ExpectResultMatching waits for the next result of the check and fails the
+// test unless it has the given status and its output matches the pattern
+func (h *Harness) ExpectResultMatching(
+	name string,
+	status stdCheck.ExitStatus,
+	pattern string,
+) sensu.CheckResponse {
+	r := h.NextResult(name)
+
+	AssertStatus(h.t, r, status)
+	AssertOutputMatches(h.t, r, pattern)
+
+	return r
+}
@@ -0,0 +1,248 @@
+// Package sensutest runs a client end to end on an in-memory transport and
+// a fake clock, so the checks can be tested along with the client
+// scheduling and the published results
+package sensutest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu"
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+)
+
+const pollInterval = time.Millisecond
+
+// DefaultTimeout bounds the wait for the asynchronous operations of the
+// client, such as the check executions
+var DefaultTimeout = 5 * time.Second
+
+// Epoch is the time the fake clock of the harnesses starts at
+var Epoch = time.Unix(1479057736, 0)
+
+// Harness runs a client on a memory transport and a fake clock
+type Harness struct {
+	Client    *sensu.Client
+	Transport *memory.Transport
+	Clock     *clock.Fake
+	Timeout   time.Duration
+
+	t          testing.TB
+	done       chan error
+	results    map[string]int
+	keepAlives int
+}
+
+// Config loads a configuration from its JSON definition, the environment
+// variables are applied like for a configuration file
+func Config(t testing.TB, definition string) *sensu.Config {
+	f, err := ioutil.TempFile("", "sensutest")
+
+	if err != nil {
+		t.Fatalf("Can't create the configuration file: %s", err)
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(definition); err != nil {
+		t.Fatalf("Can't write the configuration file: %s", err)
+	}
+
+	f.Close()
+
+	cfg, err := sensu.NewConfigFromFile(nil, f.Name())
+
+	if err != nil {
+		t.Fatalf("Invalid configuration: %s", err)
+	}
+
+	return cfg
+}
+
+// New creates a harness for the given configuration, the client is not
+// started
+func New(t testing.TB, cfg *sensu.Config) *Harness {
+	transport := memory.NewTransport()
+	client := sensu.NewClient(transport, cfg)
+	client.Clock = clock.NewFake(Epoch)
+	transport.Clock = client.Clock
+
+	return &Harness{
+		Client:    client,
+		Transport: transport,
+		Clock:     client.Clock.(*clock.Fake),
+		Timeout:   DefaultTimeout,
+		t:         t,
+		done:      make(chan error, 1),
+		results:   make(map[string]int),
+	}
+}
+
+// Start starts the client and waits for the subscriptions, the schedules
+// and the first keepalive
+func (h *Harness) Start() {
+	go func() { h.done <- h.Client.Start() }()
+
+	tickers := 1
+
+	for _, definition := range h.Client.Config.CheckDefinitions() {
+		if definition.Standalone {
+			tickers++
+		}
+	}
+
+	h.waitFor("the client to start", func() bool {
+		if h.Clock.Tickers() < tickers {
+			return false
+		}
+
+		for _, subscription := range h.Client.Config.Client().Subscriptions {
+			if h.Transport.Subscribers(subscription) == 0 {
+				return false
+			}
+		}
+
+		return len(h.Transport.Published("keepalives")) > 0
+	})
+}
+
+// Stop stops the client like SIGTERM does
+func (h *Harness) Stop() {
+	h.Client.Stop()
+
+	select {
+	case err := <-h.done:
+		if err != nil {
+			h.t.Errorf("Expected the client to stop without error but got \"%s\" instead!", err)
+		}
+	case <-time.After(h.Timeout):
+		h.t.Fatal("Timed out waiting for the client to stop")
+	}
+}
+
+// Advance moves the fake clock forward, firing the standalone checks and
+// the keepalives which are due
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Request publishes a check request to a subscription, the request is
+// issued now if its issued timestamp is not set
+func (h *Harness) Request(subscription string, request *check.Request) {
+	r := *request
+
+	if r.Issued == 0 {
+		r.Issued = h.Clock.Now().Unix()
+	}
+
+	p, err := json.Marshal(&r)
+
+	if err != nil {
+		h.t.Fatalf("Invalid check request: %s", err)
+	}
+
+	if err := h.Transport.Publish("fanout", subscription, "", p); err != nil {
+		h.t.Fatalf("Can't publish the check request: %s", err)
+	}
+}
+
+// Results returns all the check results published by the client
+func (h *Harness) Results() []sensu.CheckResponse {
+	results := []sensu.CheckResponse{}
+
+	for _, message := range h.Transport.Published("results") {
+		var result sensu.CheckResponse
+
+		if err := json.Unmarshal(message.Body, &result); err != nil {
+			h.t.Fatalf("Invalid check result %s: %s", message.Body, err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// NextResult waits for the next result of the check with the given name,
+// each result is returned once
+func (h *Harness) NextResult(name string) sensu.CheckResponse {
+	var result sensu.CheckResponse
+
+	h.waitFor("a result of "+name, func() bool {
+		seen := 0
+
+		for _, r := range h.Results() {
+			if r.Check.CheckRequest == nil || r.Check.Check == nil || r.Check.Name != name {
+				continue
+			}
+
+			if seen == h.results[name] {
+				result = r
+				h.results[name]++
+
+				return true
+			}
+
+			seen++
+		}
+
+		return false
+	})
+
+	return result
+}
+
+// KeepAlives returns all the keepalives published by the client
+func (h *Harness) KeepAlives() []map[string]interface{} {
+	keepAlives := []map[string]interface{}{}
+
+	for _, message := range h.Transport.Published("keepalives") {
+		var keepAlive map[string]interface{}
+
+		if err := json.Unmarshal(message.Body, &keepAlive); err != nil {
+			h.t.Fatalf("Invalid keepalive %s: %s", message.Body, err)
+		}
+
+		keepAlives = append(keepAlives, keepAlive)
+	}
+
+	return keepAlives
+}
+
+// NextKeepAlive waits for the next keepalive, each keepalive is returned
+// once
+func (h *Harness) NextKeepAlive() map[string]interface{} {
+	var keepAlive map[string]interface{}
+
+	h.waitFor("a keepalive", func() bool {
+		keepAlives := h.KeepAlives()
+
+		if len(keepAlives) <= h.keepAlives {
+			return false
+		}
+
+		keepAlive = keepAlives[h.keepAlives]
+		h.keepAlives++
+
+		return true
+	})
+
+	return keepAlive
+}
+
+func (h *Harness) waitFor(what string, condition func() bool) {
+	deadline := time.Now().Add(h.Timeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("Timed out waiting for %s", what)
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,75 @@
+package sensutest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/handler"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+func TestHarness(t *testing.T) {
+	calls := 0
+
+	check.Store["sensutest"] = &check.ExtensionCheck{
+		Function: func() check.ExtensionCheckResult {
+			calls++
+			return handler.Warning("disk usage is 85%")
+		},
+	}
+	defer delete(check.Store, "sensutest")
+
+	h := New(
+		t,
+		Config(
+			t,
+			`{
+				"client": {"name": "test", "subscriptions": ["linux"]},
+				"checks": [
+					{"name": "echo", "command": "echo foo", "standalone": true, "interval": 10}
+				]
+			}`,
+		),
+	)
+
+	h.Start()
+	defer h.Stop()
+
+	if name := h.NextKeepAlive()["name"]; name != "test" {
+		t.Errorf("Expected keepalive name to be test but got %v instead!", name)
+	}
+
+	h.Request(
+		"linux",
+		&check.Request{
+			Definition: &check.Definition{
+				Check: &stdCheck.Check{Name: "disk", Extension: "sensutest"},
+			},
+		},
+	)
+
+	r := h.ExpectResultMatching("disk", stdCheck.Warning, `^WARNING: disk usage is \d+%$`)
+
+	if r.Client != "test" || r.Check.Issued != Epoch.Unix() {
+		t.Errorf("Unexpected check result: %+v", r)
+	}
+
+	h.Advance(10 * time.Second)
+	r = h.ExpectResult("echo", stdCheck.Success, "foo")
+
+	if r.Check.Issued != Epoch.Add(10*time.Second).Unix() {
+		t.Errorf("Expected the check to be issued by the fake clock but got %d instead!", r.Check.Issued)
+	}
+
+	h.Advance(10 * time.Second)
+	h.ExpectResult("echo", stdCheck.Success, "foo")
+
+	if timestamp := h.NextKeepAlive()["timestamp"]; timestamp != float64(Epoch.Add(20*time.Second).Unix()) {
+		t.Errorf("Expected the keepalive to be sent after 20s but got %v instead!", timestamp)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the extension to be called once but got %d instead!", calls)
+	}
+}
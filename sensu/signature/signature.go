@@ -0,0 +1,167 @@
+// Package signature signs the JSON payloads exchanged with the Sensu server
+// with a shared secret, and verifies them. The server side handlers can use
+// it to check the results and the keepalives published by the client.
+//
+// The signature is an HMAC-SHA256 of the canonical form of the payload,
+// stored with the ID of its key in the payload "signature" attribute:
+//
+//	{"client": "foo", "check": {...}, "signature": {"key_id": "2017-08", "algorithm": "hmac-sha256", "value": "5d41..."}}
+//
+// The canonical form is the payload without its "signature" attribute,
+// with the object keys sorted, without insignificant whitespace and with the
+// numbers written as in the payload.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Algorithm is the only supported signature algorithm
+	Algorithm = "hmac-sha256"
+
+	attribute = "signature"
+)
+
+var (
+	ErrMissingSignature = errors.New("The payload isn't signed")
+	ErrInvalidSignature = errors.New("The payload signature doesn't match")
+	errNotAnObject      = errors.New("The payload isn't a JSON object")
+)
+
+// Signature is the signature attribute of the signed payloads
+type Signature struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// Keys are the shared secrets indexed by their ID. Several keys allow their
+// rotation: the payloads signed by any of them are valid.
+type Keys map[string]string
+
+// Canonicalize returns the canonical form of a JSON object, the signed one
+func Canonicalize(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	if object == nil {
+		return nil, errNotAnObject
+	}
+
+	delete(object, attribute)
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(object); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func compute(payload []byte, secret string) ([]byte, error) {
+	canonical, err := Canonicalize(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(canonical)
+
+	return mac.Sum(nil), nil
+}
+
+// Sign adds the signature of the payload with the given key to it, a
+// previous signature is replaced
+func Sign(payload []byte, keyID, secret string) ([]byte, error) {
+	sum, err := compute(payload, secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]json.RawMessage
+
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, err
+	}
+
+	signature, err := json.Marshal(
+		Signature{KeyID: keyID, Algorithm: Algorithm, Value: hex.EncodeToString(sum)},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	object[attribute] = signature
+
+	return json.Marshal(object)
+}
+
+// Verify checks the payload is signed by one of the keys, it returns the
+// signature on success
+func Verify(payload []byte, keys Keys) (*Signature, error) {
+	var object map[string]json.RawMessage
+
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, err
+	}
+
+	raw, ok := object[attribute]
+
+	if !ok {
+		return nil, ErrMissingSignature
+	}
+
+	var signature Signature
+
+	if err := json.Unmarshal(raw, &signature); err != nil {
+		return nil, fmt.Errorf("Invalid signature: %s", err.Error())
+	}
+
+	if signature.Algorithm != Algorithm {
+		return nil, fmt.Errorf("Unsupported signature algorithm: %s", signature.Algorithm)
+	}
+
+	secret, ok := keys[signature.KeyID]
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown signature key: %s", signature.KeyID)
+	}
+
+	value, err := hex.DecodeString(signature.Value)
+
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	sum, err := compute(payload, secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(value, sum) {
+		return nil, ErrInvalidSignature
+	}
+
+	return &signature, nil
+}
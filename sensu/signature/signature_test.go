@@ -0,0 +1,135 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/upfluence/goutils/testing/utils"
+)
+
+func TestCanonicalize(t *testing.T) {
+	for _, tCase := range []struct {
+		in  string
+		out string
+	}{
+		{`{}`, `{}`},
+		{`{"b": 1, "a": {"d": [1.0, 2], "c": "<&>"}}`, `{"a":{"c":"<&>","d":[1.0,2]},"b":1}`},
+		{
+			`{"client": "foo", "signature": {"key_id": "k1"}}`,
+			`{"client":"foo"}`,
+		},
+	} {
+		out, err := Canonicalize([]byte(tCase.in))
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(string(out), tCase.out, "canonical form", t)
+	}
+
+	for _, in := range []string{`[]`, `null`, `{`} {
+		if _, err := Canonicalize([]byte(in)); err == nil {
+			t.Errorf("Expected an error for %s but got nil instead!", in)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	payload := []byte(`{"client": "foo", "check": {"name": "disk", "status": 0}}`)
+	keys := Keys{"2017-05": "old secret", "2017-08": "new secret"}
+
+	for id, secret := range keys {
+		signed, err := Sign(payload, id, secret)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		signature, err := Verify(signed, keys)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(signature.KeyID, id, "key ID", t)
+		utils.ValidateStringParameter(signature.Algorithm, Algorithm, "algorithm", t)
+
+		resigned, err := Sign(signed, id, secret)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(string(resigned), string(signed), "payload", t)
+	}
+
+	signed, err := Sign(payload, "2017-08", "new secret")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	var object map[string]json.RawMessage
+
+	if err := json.Unmarshal(signed, &object); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	// The signature doesn't depend on the key order nor on the whitespaces
+	reordered := []byte(`{
+		"check": {"status": 0, "name": "disk"},
+		"signature": ` + string(object["signature"]) + `,
+		"client": "foo"
+	}`)
+
+	if _, err := Verify(reordered, keys); err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	keys := Keys{"k1": "secret"}
+	signed, err := Sign([]byte(`{"client": "foo"}`), "k1", "secret")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	tampered := bytes.Replace(signed, []byte(`"foo"`), []byte(`"bar"`), 1)
+
+	for _, tCase := range []struct {
+		payload string
+		keys    Keys
+		err     string
+	}{
+		{`{"client": "foo"}`, keys, ErrMissingSignature.Error()},
+		{string(tampered), keys, ErrInvalidSignature.Error()},
+		{string(signed), Keys{"k1": "other secret"}, ErrInvalidSignature.Error()},
+		{string(signed), Keys{"k2": "secret"}, "Unknown signature key: k1"},
+		{
+			`{"client": "foo", "signature": {"key_id": "k1", "algorithm": "md5", "value": "00"}}`,
+			keys,
+			"Unsupported signature algorithm: md5",
+		},
+		{
+			`{"client": "foo", "signature": {"key_id": "k1", "algorithm": "hmac-sha256", "value": "zz"}}`,
+			keys,
+			ErrInvalidSignature.Error(),
+		},
+	} {
+		signature, err := Verify([]byte(tCase.payload), tCase.keys)
+
+		if err == nil {
+			t.Errorf("Expected an error for %s but got nil instead!", tCase.payload)
+			continue
+		}
+
+		utils.ValidateStringParameter(err.Error(), tCase.err, "error", t)
+
+		if signature != nil {
+			t.Errorf("Expected a nil signature but got %+v instead!", signature)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package sensu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/signature"
+)
+
+const (
+	defaultRequestMaxAge = 5 * time.Minute
+
+	// maxSeenRequests bounds the signatures remembered until they expire
+	maxSeenRequests = 10000
+)
+
+var (
+	errRequestNotIssued  = errors.New("The check request has no issued timestamp")
+	errNoVerificationKey = errors.New("The check requests can't be verified without signing keys")
+	errRequestReplayed   = errors.New("The check request was already received")
+	errTooManyRequests   = errors.New("Too many check requests received before their expiry")
+)
+
+// seenRequests holds the signatures of the accepted check requests until
+// their issued timestamp is too old for them to be accepted again
+type seenRequests struct {
+	mu       sync.Mutex
+	expiries map[string]time.Time
+}
+
+func (r *seenRequests) add(value string, expiry, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.expiries == nil {
+		r.expiries = make(map[string]time.Time)
+	}
+
+	if _, ok := r.expiries[value]; ok {
+		return errRequestReplayed
+	}
+
+	if len(r.expiries) >= maxSeenRequests {
+		for v, e := range r.expiries {
+			if !e.After(now) {
+				delete(r.expiries, v)
+			}
+		}
+	}
+
+	// The requests are rejected rather than forgetting the signatures which
+	// could then be replayed
+	if len(r.expiries) >= maxSeenRequests {
+		return errTooManyRequests
+	}
+
+	r.expiries[value] = expiry
+
+	return nil
+}
+
+// signingConfig is the signing block, the results and the keepalives are
+// signed with the key_id key when it is set. The other keys are the
+// previous or the next ones during a rotation.
+type signingConfig struct {
+	KeyID string         `json:"key_id,omitempty"`
+	Keys  signature.Keys `json:"keys,omitempty"`
+	// Rejects the check requests which aren't signed by one of the keys
+	VerifyRequests bool `json:"verify_requests,omitempty"`
+	// Seconds after which a signed check request is rejected as a replay
+	RequestMaxAge int64 `json:"request_max_age,omitempty"`
+
+	seen seenRequests
+}
+
+func (c *signingConfig) enabled() bool {
+	return c != nil && c.KeyID != ""
+}
+
+func (c *signingConfig) requestMaxAge() time.Duration {
+	if c != nil && c.RequestMaxAge > 0 {
+		return time.Duration(c.RequestMaxAge) * time.Second
+	}
+
+	return defaultRequestMaxAge
+}
+
+// verifyRequest checks the check request is signed by one of the keys, was
+// issued recently, in both directions to bear the clocks skew, and wasn't
+// already accepted. The subscription isn't part of the signed payload, a
+// request is accepted once whichever subscription it is received on.
+func (c *signingConfig) verifyRequest(blob []byte, issued int64, now time.Time) error {
+	if c == nil || !c.VerifyRequests {
+		return nil
+	}
+
+	s, err := signature.Verify(blob, c.Keys)
+
+	if err != nil {
+		return err
+	}
+
+	if issued == 0 {
+		return errRequestNotIssued
+	}
+
+	maxAge := c.requestMaxAge()
+
+	if age := now.Sub(time.Unix(issued, 0)); age > maxAge || age < -maxAge {
+		return fmt.Errorf(
+			"The check request signed by %s was issued %s ago, the maximum age is %s",
+			s.KeyID,
+			age,
+			maxAge,
+		)
+	}
+
+	return c.seen.add(s.Value, time.Unix(issued, 0).Add(maxAge), now)
+}
+
+func (c *signingConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+
+	for id, secret := range c.Keys {
+		if secret == "" {
+			return fmt.Errorf("Empty secret for the signing key %s", id)
+		}
+	}
+
+	if _, ok := c.Keys[c.KeyID]; c.KeyID != "" && !ok {
+		return fmt.Errorf("Unknown signing key: %s", c.KeyID)
+	}
+
+	if c.VerifyRequests && len(c.Keys) == 0 {
+		return errNoVerificationKey
+	}
+
+	if c.RequestMaxAge < 0 {
+		return fmt.Errorf("Invalid signing request_max_age: %d", c.RequestMaxAge)
+	}
+
+	return nil
+}
+
+func (c *Config) signing() *signingConfig {
+	if c.config.Signing == nil {
+		c.config.Signing = &signingConfig{}
+	}
+
+	return c.config.Signing
+}
+
+func (c *Config) applySigningEnv() error {
+	if v := os.Getenv("SENSU_SIGNING_KEYS"); v != "" {
+		var keys signature.Keys
+
+		if err := json.Unmarshal([]byte(v), &keys); err != nil {
+			return fmt.Errorf("Invalid value for SENSU_SIGNING_KEYS: %s", err.Error())
+		}
+
+		c.signing().Keys = keys
+	}
+
+	if v := os.Getenv("SENSU_SIGNING_KEY_ID"); v != "" {
+		c.signing().KeyID = v
+	}
+
+	if v := os.Getenv("SENSU_SIGNING_VERIFY_REQUESTS"); v != "" {
+		verify, err := strconv.ParseBool(v)
+
+		if err != nil {
+			return fmt.Errorf(
+				"Invalid value for SENSU_SIGNING_VERIFY_REQUESTS: %s",
+				err.Error(),
+			)
+		}
+
+		c.signing().VerifyRequests = verify
+	}
+
+	return nil
+}
+
+func (c *Client) signingConfig() *signingConfig {
+	if c.Config.config == nil {
+		return nil
+	}
+
+	return c.Config.config.Signing
+}
+
+// sign adds the signature to the payload, it is left untouched if the
+// signing is disabled
+func (c *signingConfig) sign(payload []byte) ([]byte, error) {
+	if !c.enabled() {
+		return payload, nil
+	}
+
+	return signature.Sign(payload, c.KeyID, c.Keys[c.KeyID])
+}
+
+// sign adds the signature to the payload published to the server
+func (c *Client) sign(payload []byte) ([]byte, error) {
+	return c.signingConfig().sign(payload)
+}
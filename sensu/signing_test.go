@@ -0,0 +1,294 @@
+package sensu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/upfluence/goutils/testing/utils"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+	"github.com/upfluence/sensu-client-go/sensu/signature"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+)
+
+var testSigningKeys = signature.Keys{"2017-05": "old secret", "2017-08": "new secret"}
+
+func TestSigningConfigValidation(t *testing.T) {
+	for _, tCase := range []struct {
+		config *signingConfig
+		valid  bool
+	}{
+		{nil, true},
+		{&signingConfig{}, true},
+		{&signingConfig{Keys: testSigningKeys}, true},
+		{&signingConfig{KeyID: "2017-08", Keys: testSigningKeys}, true},
+		{&signingConfig{KeyID: "2017-09", Keys: testSigningKeys}, false},
+		{&signingConfig{KeyID: "2017-08"}, false},
+		{&signingConfig{Keys: signature.Keys{"2017-08": ""}}, false},
+		{&signingConfig{VerifyRequests: true, Keys: testSigningKeys}, true},
+		{&signingConfig{VerifyRequests: true}, false},
+		{&signingConfig{RequestMaxAge: -1}, false},
+	} {
+		if err := tCase.config.validate(); (err == nil) != tCase.valid {
+			t.Errorf(
+				"Expected validity of %+v to be %t but got \"%v\" instead!",
+				tCase.config,
+				tCase.valid,
+				err,
+			)
+		}
+	}
+}
+
+func TestSigningConfigFromEnvVars(t *testing.T) {
+	for k, v := range map[string]string{
+		"SENSU_SIGNING_KEY_ID": "2017-08",
+		"SENSU_SIGNING_KEYS":   `{"2017-05": "old secret", "2017-08": "new secret"}`,
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(cfg.signing().KeyID, "2017-08", "key ID", t)
+
+	if !reflect.DeepEqual(cfg.signing().Keys, testSigningKeys) {
+		t.Errorf(
+			"Expected the keys to be %v but got %v instead!",
+			testSigningKeys,
+			cfg.signing().Keys,
+		)
+	}
+
+	os.Setenv("SENSU_SIGNING_KEYS", "secret")
+
+	if _, err := NewConfigFromFile(nil, "testdata/client-attributes.json"); err == nil {
+		t.Error("Expected an error for invalid keys but got nil instead!")
+	}
+}
+
+func TestSignedPayloads(t *testing.T) {
+	c := newTestClient(&configPayload{Signing: &signingConfig{KeyID: "2017-08", Keys: testSigningKeys}})
+	transport := c.Transport.(*memory.Transport)
+
+	NewKeepAlive(c).publishKeepAlive()
+
+	if err := NewStandalone(
+		&stdCheck.Check{Name: "foo", Command: "printf bar"},
+		c,
+	).execute(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	for _, exchange := range []string{"keepalives", "results"} {
+		messages := transport.Published(exchange)
+
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 %s message but got %d instead!", exchange, len(messages))
+		}
+
+		s, err := signature.Verify(messages[0].Body, testSigningKeys)
+
+		if err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(s.KeyID, "2017-08", "key ID", t)
+	}
+}
+
+func TestUnsignedPayloads(t *testing.T) {
+	c := newTestClient(&configPayload{Signing: &signingConfig{Keys: testSigningKeys}})
+
+	NewKeepAlive(c).publishKeepAlive()
+
+	body := lastMessage(c.Transport.(*memory.Transport)).Body
+
+	if _, err := signature.Verify(body, testSigningKeys); err != signature.ErrMissingSignature {
+		t.Errorf(
+			"Expected error to be \"%s\" but got \"%v\" instead!",
+			signature.ErrMissingSignature,
+			err,
+		)
+	}
+}
+
+func signRequest(t *testing.T, request, keyID string) []byte {
+	signed, err := signature.Sign([]byte(request), keyID, testSigningKeys[keyID])
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	return signed
+}
+
+func TestHandleSignedRequests(t *testing.T) {
+	now := time.Unix(1479057736, 0)
+	request := `{"name":"foo","command":"printf remote","issued":%d}`
+	forged, err := signature.Sign(
+		[]byte(fmt.Sprintf(request, now.Unix())),
+		"2017-08",
+		"forged secret",
+	)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	for _, tCase := range []struct {
+		blob     []byte
+		accepted bool
+	}{
+		{signRequest(t, fmt.Sprintf(request, now.Unix()), "2017-08"), true},
+		{signRequest(t, fmt.Sprintf(request, now.Unix()-60), "2017-05"), true},
+		{signRequest(t, fmt.Sprintf(request, now.Unix()+60), "2017-08"), true},
+		{[]byte(fmt.Sprintf(request, now.Unix())), false},
+		{forged, false},
+		{signRequest(t, fmt.Sprintf(request, now.Unix()-301), "2017-08"), false},
+		{signRequest(t, fmt.Sprintf(request, now.Unix()+301), "2017-08"), false},
+		{signRequest(t, `{"name":"foo","command":"printf remote"}`, "2017-08"), false},
+	} {
+		c := newTestClient(&configPayload{Signing: &signingConfig{Keys: testSigningKeys, VerifyRequests: true}})
+		c.Clock = clock.NewFake(now)
+
+		NewSubscriber("test", c).handleMessage(tCase.blob)
+
+		if n := len(c.Transport.(*memory.Transport).Published("results")); (n == 1) != tCase.accepted {
+			t.Errorf(
+				"Expected acceptance of %s to be %t but got %d results instead!",
+				tCase.blob,
+				tCase.accepted,
+				n,
+			)
+		}
+	}
+}
+
+func TestHandleReplayedRequests(t *testing.T) {
+	now := time.Unix(1479057736, 0)
+	c := newTestClient(&configPayload{Signing: &signingConfig{Keys: testSigningKeys, VerifyRequests: true}})
+	c.Clock = clock.NewFake(now)
+	blob := signRequest(
+		t,
+		fmt.Sprintf(`{"name":"foo","command":"printf remote","issued":%d}`, now.Unix()),
+		"2017-08",
+	)
+
+	for _, subscription := range []string{"test", "test", "other"} {
+		NewSubscriber(subscription, c).handleMessage(blob)
+	}
+
+	if n := len(c.Transport.(*memory.Transport).Published("results")); n != 1 {
+		t.Errorf("Expected the request to be executed once but got %d results instead!", n)
+	}
+}
+
+func TestSeenRequestsBound(t *testing.T) {
+	var seen seenRequests
+
+	now := time.Unix(1479057736, 0)
+
+	for i := 0; i < maxSeenRequests; i++ {
+		if err := seen.add(strconv.Itoa(i), now.Add(time.Duration(i+1)*time.Second), now); err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+	}
+
+	if err := seen.add("foo", now.Add(time.Minute), now); err != errTooManyRequests {
+		t.Errorf("Expected error \"%s\" but got \"%v\" instead!", errTooManyRequests, err)
+	}
+
+	// The expired signatures make room for the new ones
+	if err := seen.add("foo", now.Add(time.Minute), now.Add(time.Second)); err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if err := seen.add("foo", now.Add(time.Minute), now.Add(time.Second)); err != errRequestReplayed {
+		t.Errorf("Expected error \"%s\" but got \"%v\" instead!", errRequestReplayed, err)
+	}
+}
+
+func TestVerifyRequestsDisabled(t *testing.T) {
+	c := newTestClient(&configPayload{Signing: &signingConfig{Keys: testSigningKeys}})
+
+	NewSubscriber("test", c).handleMessage(
+		[]byte(`{"name":"foo","command":"printf remote","issued":1479057736}`),
+	)
+
+	if lastMessage(c.Transport.(*memory.Transport)) == nil {
+		t.Error("Expected a check result to be published but got nothing!")
+	}
+}
+
+func TestSignedAPIResults(t *testing.T) {
+	var (
+		bodies = make(chan []byte, 1)
+		server = httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				bodies <- body
+				w.WriteHeader(http.StatusAccepted)
+			},
+		))
+	)
+
+	defer server.Close()
+
+	os.Setenv("SENSU_API_URL", server.URL)
+	defer os.Unsetenv("SENSU_API_URL")
+
+	c := newTestClient(&configPayload{Signing: &signingConfig{KeyID: "2017-08", Keys: testSigningKeys}})
+	c.Config.config.Transport = &transportConfig{Name: "http"}
+
+	tr, err := NewTransport(c.Config)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	tr.Connect()
+	c.Transport = tr
+
+	if err := NewStandalone(
+		&stdCheck.Check{Name: "foo", Command: "printf bar"},
+		c,
+	).execute(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	body := <-bodies
+
+	s, err := signature.Verify(body, testSigningKeys)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" for %s instead!", err, body)
+	}
+
+	utils.ValidateStringParameter(s.KeyID, "2017-08", "key ID", t)
+
+	var result struct {
+		Name   string `json:"name"`
+		Source string `json:"source"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(result.Name, "foo", "check name", t)
+	utils.ValidateStringParameter(result.Source, "Test", "source", t)
+}
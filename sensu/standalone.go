@@ -1,36 +1,45 @@
 package sensu
 
 import (
-	"bytes"
 	"encoding/json"
 	"time"
 
 	"github.com/upfluence/goutils/log"
-	"github.com/upfluence/sensu-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 )
 
 type Standalone struct {
-	check     *check.Check
+	check     *check.Definition
 	client    *Client
 	closeChan chan bool
 }
 
-func NewStandalone(check *check.Check, c *Client) *Standalone {
-	return &Standalone{check, c, make(chan bool)}
+func NewStandalone(sensuCheck *stdCheck.Check, c *Client) *Standalone {
+	return NewStandaloneFromDefinition(&check.Definition{Check: sensuCheck}, c)
+}
+
+// NewStandaloneFromDefinition schedules a check with the attributes specific
+// to this client, such as its timeout and its process settings
+func NewStandaloneFromDefinition(definition *check.Definition, c *Client) *Standalone {
+	return &Standalone{definition, c, make(chan bool)}
 }
 
 func (s *Standalone) Start() error {
-	t := time.Tick(defaultInterval)
+	interval := defaultInterval
 
 	if s.check.Interval > 0 {
-		t = time.Tick(time.Duration(s.check.Interval) * time.Second)
+		interval = time.Duration(s.check.Interval) * time.Second
 	}
 
+	t := s.client.clock().NewTicker(interval)
+	defer t.Stop()
+
 	log.Noticef("Setup standalone check %s", s.check.Name)
 
 	for {
 		select {
-		case <-t:
+		case <-t.C():
 			if err := s.execute(); err != nil {
 				log.Errorf("Something went wrong: %s", err.Error())
 			}
@@ -46,12 +55,20 @@ func (s *Standalone) Close() {
 }
 
 func (s *Standalone) execute() error {
-	if p, err := json.Marshal(s.check); err == nil {
-		log.Infof("Check received: %s", bytes.NewBuffer(p).String())
+	// The process settings aren't logged, their env may hold secrets
+	if p, err := json.Marshal(
+		&check.Definition{Check: s.check.Check, Timeout: s.check.Timeout},
+	); err == nil {
+		log.Infof("Check received: %s", s.client.redactPayload(p))
 	}
 
+	definition := *s.check
+	definition.Process = s.client.checkProcess(s.check)
+
 	output, err := executeCheck(
-		&check.CheckRequest{Check: s.check, Issued: time.Now().Unix()},
+		&check.Request{Definition: &definition, Issued: s.client.clock().Now().Unix()},
+		s.client.clock(),
+		nil,
 	)
 
 	if err != nil {
@@ -62,10 +79,14 @@ func (s *Standalone) execute() error {
 		&CheckResponse{Check: *output, Client: s.client.Config.Client().Name},
 	)
 
+	if err == nil {
+		p, err = s.client.sign(p)
+	}
+
 	if err != nil {
 		return err
 	} else {
-		log.Noticef("Payload sent: %s", bytes.NewBuffer(p).String())
+		log.Noticef("Payload sent: %s", s.client.redactPayload(p))
 		s.client.Transport.Publish("direct", "results", "", p)
 	}
 
@@ -1,14 +1,21 @@
 package sensu
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
 	stdCheck "github.com/upfluence/sensu-go/sensu/check"
 	stdClient "github.com/upfluence/sensu-go/sensu/client"
 )
 
 func TestMissingCommandKey(t *testing.T) {
-	standaloneProcessor := &Standalone{check: &stdCheck.Check{}}
+	standaloneProcessor := &Standalone{
+		check: &check.Definition{Check: &stdCheck.Check{}},
+	}
 
 	err := standaloneProcessor.execute()
 
@@ -21,67 +28,51 @@ func TestMissingCommandKey(t *testing.T) {
 	}
 }
 
-type transportPublishParameters struct {
-	exchangeType, exchangeName, key string
-	message                         []byte
-}
+func newTestTransport() *memory.Transport {
+	transport := memory.NewTransport()
+	transport.Connect()
 
-type dummyTransport struct {
-	publishParameters *transportPublishParameters
+	return transport
 }
 
-func (t *dummyTransport) Connect() error {
-	return nil
-}
+// newTestConfig wraps the payload, its client is named Test unless it is set
+func newTestConfig(payload *configPayload) *Config {
+	if payload.Client == nil {
+		payload.Client = &clientConfig{Client: &stdClient.Client{Name: "Test"}}
+	}
 
-func (t *dummyTransport) IsConnected() bool {
-	return true
+	return &Config{config: payload}
 }
 
-func (t *dummyTransport) Close() error {
-	return nil
+// newTestClient returns a client of the payload publishing through a
+// connected memory transport
+func newTestClient(payload *configPayload) *Client {
+	return &Client{Transport: newTestTransport(), Config: newTestConfig(payload)}
 }
 
-func (t *dummyTransport) Publish(
-	exchangeType,
-	exchangeName,
-	key string,
-	message []byte) error {
+// lastMessage returns the last message published through the transport,
+// nil if there is none
+func lastMessage(transport *memory.Transport) *memory.Message {
+	messages := transport.Messages()
 
-	t.publishParameters = &transportPublishParameters{
-		exchangeType,
-		exchangeName,
-		key,
-		message,
+	if len(messages) == 0 {
+		return nil
 	}
 
-	return nil
-}
-
-func (t *dummyTransport) Subscribe(
-	key,
-	exchangeName,
-	queueName string,
-	messageChan chan []byte,
-	stopChan chan bool) error {
-
-	return nil
-}
-
-func (t *dummyTransport) GetClosingChan() chan bool {
-	return nil
+	return &messages[len(messages)-1]
 }
 
 func TestRunCommand(t *testing.T) {
+	transport := newTestTransport()
 	standaloneProcessor := NewStandalone(
 		&stdCheck.Check{Command: "ls"},
 		&Client{
 			Config: &Config{
 				config: &configPayload{
-					Client: &stdClient.Client{Name: "Test"},
+					Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
 				},
 			},
-			Transport: &dummyTransport{},
+			Transport: transport,
 		},
 	)
 
@@ -91,41 +82,102 @@ func TestRunCommand(t *testing.T) {
 		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
 	}
 
-	expectedPublishParams := transportPublishParameters{
-		exchangeType: "direct",
-		exchangeName: "results",
-		key:          "",
+	message := lastMessage(transport)
+
+	if message == nil {
+		t.Fatal("Expected a check result to be published but got nothing!")
 	}
 
-	publishParams :=
-		standaloneProcessor.client.Transport.(*dummyTransport).publishParameters
+	expectedMessage := memory.Message{
+		ExchangeType: "direct",
+		ExchangeName: "results",
+		Key:          "",
+	}
 
-	if publishParams.exchangeType != expectedPublishParams.exchangeType {
+	if message.ExchangeType != expectedMessage.ExchangeType {
 		t.Errorf(
 			"Expected exchange type to be \"%s\" but got \"%s\" instead!",
-			expectedPublishParams.exchangeType,
-			publishParams.exchangeType,
+			expectedMessage.ExchangeType,
+			message.ExchangeType,
 		)
 	}
 
-	if publishParams.exchangeName != expectedPublishParams.exchangeName {
+	if message.ExchangeName != expectedMessage.ExchangeName {
 		t.Errorf(
 			"Expected exchange name to be \"%s\" but got \"%s\" instead!",
-			expectedPublishParams.exchangeName,
-			publishParams.exchangeName,
+			expectedMessage.ExchangeName,
+			message.ExchangeName,
 		)
 	}
 
-	if publishParams.key != expectedPublishParams.key {
+	if message.Key != expectedMessage.Key {
 		t.Errorf(
 			"Expected key to be \"%s\" but got \"%s\" instead!",
-			expectedPublishParams.key,
-			publishParams.key,
+			expectedMessage.Key,
+			message.Key,
 		)
 	}
 
 	// Not particularly relevant here to validate the contents of the message
-	if publishParams.message == nil {
+	if message.Body == nil {
 		t.Errorf("Expected message type to be initialized but got nil instead!")
 	}
 }
+
+func TestStandaloneStart(t *testing.T) {
+	transport := newTestTransport()
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+	standaloneProcessor := NewStandalone(
+		&stdCheck.Check{Name: "foo", Command: "printf bar", Interval: 10},
+		&Client{
+			Config: &Config{
+				config: &configPayload{
+					Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+				},
+			},
+			Transport: transport,
+			Clock:     clk,
+		},
+	)
+
+	go standaloneProcessor.Start()
+	defer standaloneProcessor.Close()
+
+	for i := 0; clk.Tickers() == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the standalone check to be scheduled!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	clk.Advance(9 * time.Second)
+
+	if n := len(transport.Published("results")); n != 0 {
+		t.Fatalf("Expected no check result before the interval but got %d instead!", n)
+	}
+
+	clk.Advance(time.Second)
+
+	for i := 0; len(transport.Published("results")) == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected a check result to be published but got nothing!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	var payload CheckResponse
+
+	if err := json.Unmarshal(transport.Published("results")[0].Body, &payload); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	if payload.Check.Issued != 1479057746 || payload.Check.Executed != 1479057746 {
+		t.Errorf(
+			"Expected the check to be issued and executed at 1479057746 but got %d and %d instead!",
+			payload.Check.Issued,
+			payload.Check.Executed,
+		)
+	}
+}
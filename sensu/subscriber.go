@@ -1,21 +1,23 @@
 package sensu
 
 import (
-	"bytes"
 	"encoding/json"
-	"strconv"
-	"strings"
+	"errors"
 	"time"
 
 	"github.com/upfluence/goutils/log"
-	"github.com/upfluence/sensu-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/check"
 )
 
 const (
 	maxFail = 100
 	maxTime = 60 * time.Second
+
+	subscribeRetryInterval = time.Second
 )
 
+var errInvalidCheckRequest = errors.New("Check request without definition")
+
 type Subscriber struct {
 	subscription string
 	client       *Client
@@ -27,14 +29,7 @@ func NewSubscriber(subscription string, c *Client) *Subscriber {
 }
 
 func (s *Subscriber) Start() error {
-	funnel := strings.Join(
-		[]string{
-			s.client.Config.Client().Name,
-			currentVersion,
-			strconv.Itoa(int(time.Now().Unix())),
-		},
-		"-",
-	)
+	funnel := s.client.Config.subscriptionQueueName(s.subscription)
 
 	msgChan, stopChan := s.subscribe(funnel)
 	log.Noticef("Subscribed to %s", s.subscription)
@@ -45,7 +40,7 @@ func (s *Subscriber) Start() error {
 			s.handleMessage(b)
 		case <-s.closeChan:
 			log.Warningf("Graceful stop of %s", s.subscription)
-			stopChan <- true
+			close(stopChan)
 			return nil
 		}
 	}
@@ -56,16 +51,55 @@ func (s *Subscriber) Close() {
 }
 
 func (s *Subscriber) handleMessage(blob []byte) {
-	var input check.CheckRequest
+	var input check.Request
 
-	log.Noticef("Check received: %s", bytes.NewBuffer(blob).String())
+	log.Noticef("Check received: %s", s.client.redactPayload(blob))
 
 	if err := json.Unmarshal(blob, &input); err != nil {
 		log.Errorf("Something went wrong: %s", err.Error())
 		return
 	}
 
-	output, err := executeCheck(&input)
+	if input.Definition == nil || input.Check == nil {
+		log.Errorf("Something went wrong: %s", errInvalidCheckRequest.Error())
+		return
+	}
+
+	if err := s.client.signingConfig().verifyRequest(
+		blob,
+		input.Issued,
+		s.client.clock().Now(),
+	); err != nil {
+		log.Errorf(
+			"Security event: check request %s received on %s rejected: %s",
+			input.Name,
+			s.subscription,
+			err.Error(),
+		)
+		return
+	}
+
+	request := &input
+	policy := s.client.Config.client().CommandPolicy
+	definition := s.client.Config.CheckDefinition(input.Name)
+
+	if definition != nil {
+		var err error
+
+		if request, err = mergeCheckDefinition(request, definition); err != nil {
+			log.Errorf("Something went wrong: %s", err.Error())
+			return
+		}
+
+		// The commands defined locally are trusted
+		if definition.Command != "" {
+			policy = nil
+		}
+	}
+
+	request.Process = s.client.checkProcess(definition)
+
+	output, err := executeCheck(request, s.client.clock(), policy)
 
 	if err != nil {
 		log.Error(err.Error())
@@ -76,27 +110,87 @@ func (s *Subscriber) handleMessage(blob []byte) {
 		&CheckResponse{Check: *output, Client: s.client.Config.Client().Name},
 	)
 
+	if err == nil {
+		p, err = s.client.sign(p)
+	}
+
 	if err != nil {
 		log.Errorf("Something went wrong: %s", err.Error())
 	} else {
-		log.Noticef("Payload sent: %s", bytes.NewBuffer(p).String())
+		log.Noticef("Payload sent: %s", s.client.redactPayload(p))
 		s.client.Transport.Publish("direct", "results", "", p)
 	}
 }
 
+// mergeCheckDefinition emulates ruby client behavior: the attributes of the
+// check defined locally take precedence over the ones of the request, even
+// when they are set to their zero value
+func mergeCheckDefinition(
+	request *check.Request,
+	definition *check.Definition,
+) (*check.Request, error) {
+	attributes := make(map[string]json.RawMessage)
+
+	buf, err := json.Marshal(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &attributes); err != nil {
+		return nil, err
+	}
+
+	overrides, err := definition.Attributes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range overrides {
+		attributes[k] = v
+	}
+
+	if buf, err = json.Marshal(attributes); err != nil {
+		return nil, err
+	}
+
+	var merged check.Request
+
+	if err := json.Unmarshal(buf, &merged); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
 func (s *Subscriber) subscribe(funnel string) (chan []byte, chan bool) {
 	msgChan := make(chan []byte)
 	stopChan := make(chan bool)
 
 	go func() {
 		for {
-			s.client.Transport.Subscribe(
+			err := s.client.Transport.Subscribe(
 				"#",
 				s.subscription,
 				funnel,
 				msgChan,
 				stopChan,
 			)
+
+			if err != nil {
+				log.Warningf("Subscription to %s failed: %s", s.subscription, err.Error())
+			}
+
+			// Wait before subscribing again, the transport may be disconnected
+			timer := s.client.clock().NewTimer(subscribeRetryInterval)
+
+			select {
+			case <-stopChan:
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
 		}
 	}()
 
@@ -0,0 +1,305 @@
+package sensu
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/check"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	stdCheck "github.com/upfluence/sensu-go/sensu/check"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func newCheckRequest(c *stdCheck.Check, timeout int64) *check.Request {
+	return &check.Request{
+		Definition: &check.Definition{Check: c, Timeout: timeout},
+		Issued:     1479057736,
+	}
+}
+
+func decodeCheckDefinition(t *testing.T, payload string) *check.Definition {
+	var definition check.Definition
+
+	if err := json.Unmarshal([]byte(payload), &definition); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	return &definition
+}
+
+func TestMergeCheckDefinition(t *testing.T) {
+	for _, tCase := range []struct {
+		attribute  string
+		request    *check.Request
+		definition *check.Definition
+		expected   *check.Request
+	}{
+		{
+			"command",
+			newCheckRequest(&stdCheck.Check{Name: "foo", Command: "remote"}, 0),
+			&check.Definition{Check: &stdCheck.Check{Name: "foo", Command: "local"}},
+			newCheckRequest(&stdCheck.Check{Name: "foo", Command: "local"}, 0),
+		},
+		{
+			"timeout",
+			newCheckRequest(&stdCheck.Check{Name: "foo", Command: "remote"}, 10),
+			&check.Definition{Check: &stdCheck.Check{Name: "foo"}, Timeout: 5},
+			newCheckRequest(&stdCheck.Check{Name: "foo", Command: "remote"}, 5),
+		},
+		{
+			"handlers",
+			newCheckRequest(
+				&stdCheck.Check{
+					Name:     "foo",
+					Command:  "remote",
+					Handlers: []string{"default"},
+				},
+				0,
+			),
+			&check.Definition{
+				Check: &stdCheck.Check{Name: "foo", Handlers: []string{"slack"}},
+			},
+			newCheckRequest(
+				&stdCheck.Check{
+					Name:     "foo",
+					Command:  "remote",
+					Handlers: []string{"slack"},
+				},
+				0,
+			),
+		},
+		{
+			"unset attributes",
+			newCheckRequest(
+				&stdCheck.Check{
+					Name:     "foo",
+					Command:  "remote",
+					Handlers: []string{"default"},
+				},
+				10,
+			),
+			&check.Definition{Check: &stdCheck.Check{Name: "foo"}},
+			newCheckRequest(
+				&stdCheck.Check{
+					Name:     "foo",
+					Command:  "remote",
+					Handlers: []string{"default"},
+				},
+				10,
+			),
+		},
+		{
+			"zero values",
+			newCheckRequest(
+				&stdCheck.Check{
+					Name:       "foo",
+					Command:    "remote",
+					Standalone: true,
+					Handlers:   []string{"default"},
+				},
+				10,
+			),
+			decodeCheckDefinition(
+				t,
+				`{"name": "foo", "standalone": false, "handlers": [], "timeout": 0}`,
+			),
+			newCheckRequest(
+				&stdCheck.Check{Name: "foo", Command: "remote", Handlers: []string{}},
+				0,
+			),
+		},
+	} {
+		merged, err := mergeCheckDefinition(tCase.request, tCase.definition)
+
+		if err != nil {
+			t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+		}
+
+		if !reflect.DeepEqual(merged, tCase.expected) {
+			t.Errorf(
+				"%s: expected request to be %+v but got %+v instead!",
+				tCase.attribute,
+				tCase.expected.Definition,
+				merged.Definition,
+			)
+		}
+	}
+}
+
+func TestHandleMessageWithLocalDefinition(t *testing.T) {
+	transport := newTestTransport()
+	subscriber := NewSubscriber(
+		"test",
+		&Client{
+			Config: &Config{
+				config: &configPayload{
+					Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+					Checks: []*check.Definition{
+						{
+							Check: &stdCheck.Check{
+								Name:     "foo",
+								Command:  "printf local",
+								Handlers: []string{"slack"},
+							},
+						},
+					},
+				},
+			},
+			Transport: transport,
+		},
+	)
+
+	subscriber.handleMessage(
+		[]byte(`{"name":"foo","command":"printf remote","issued":1479057736}`),
+	)
+
+	if lastMessage(transport) == nil {
+		t.Fatal("Expected a check result to be published but got nothing!")
+	}
+
+	var response struct {
+		Check struct {
+			Command  string   `json:"command"`
+			Handlers []string `json:"handlers"`
+			Output   string   `json:"output"`
+			Issued   int64    `json:"issued"`
+		} `json:"check"`
+	}
+
+	if err := json.Unmarshal(lastMessage(transport).Body, &response); err != nil {
+		t.Fatalf("Expected error to be nil but got \"%s\" instead!", err)
+	}
+
+	if response.Check.Output != "local" || response.Check.Command != "printf local" {
+		t.Errorf(
+			"Expected the local command to be executed but got %+v instead!",
+			response.Check,
+		)
+	}
+
+	if !reflect.DeepEqual(response.Check.Handlers, []string{"slack"}) {
+		t.Errorf(
+			"Expected the local handlers to be used but got %v instead!",
+			response.Check.Handlers,
+		)
+	}
+
+	if response.Check.Issued != 1479057736 {
+		t.Errorf(
+			"Expected the request issued timestamp to be kept but got %d instead!",
+			response.Check.Issued,
+		)
+	}
+}
+
+func TestHandleMessageWithoutDefinition(t *testing.T) {
+	transport := newTestTransport()
+	subscriber := NewSubscriber("test", &Client{Transport: transport})
+
+	subscriber.handleMessage([]byte(`{"issued":1479057736}`))
+
+	if lastMessage(transport) != nil {
+		t.Errorf(
+			"Expected nothing to be published but got %s instead!",
+			lastMessage(transport).Body,
+		)
+	}
+}
+
+func TestSubscriberStart(t *testing.T) {
+	transport := newTestTransport()
+	subscriber := NewSubscriber(
+		"test",
+		&Client{
+			Config: &Config{
+				config: &configPayload{
+					Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+				},
+			},
+			Transport: transport,
+		},
+	)
+
+	done := make(chan error)
+
+	go func() { done <- subscriber.Start() }()
+
+	for i := 0; transport.Subscribers("test") == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the subscriber to subscribe!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	transport.Publish(
+		"fanout",
+		"test",
+		"",
+		[]byte(`{"name":"foo","command":"printf remote","issued":1479057736}`),
+	)
+
+	for i := 0; len(transport.Published("results")) == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected a check result to be published but got nothing!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	subscriber.Close()
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	for i := 0; transport.Subscribers("test") != 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the subscription to be stopped!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscriberRetry(t *testing.T) {
+	transport := memory.NewTransport()
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+	subscriber := NewSubscriber(
+		"test",
+		&Client{
+			Config: &Config{
+				config: &configPayload{
+					Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+				},
+			},
+			Transport: transport,
+			Clock:     clk,
+		},
+	)
+
+	go subscriber.Start()
+	defer subscriber.Close()
+
+	for i := 0; clk.Timers() == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the subscriber to wait before retrying!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	transport.Connect()
+	clk.Advance(subscribeRetryInterval)
+
+	for i := 0; transport.Subscribers("test") == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the subscriber to subscribe once connected!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
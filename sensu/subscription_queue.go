@@ -0,0 +1,90 @@
+package sensu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/transport/rabbitmq"
+)
+
+const (
+	// uniqueQueueNaming names the queues after the client name, the protocol
+	// version and the start time, like the ruby client does
+	uniqueQueueNaming = "unique"
+	// stableQueueNaming names the queues after the client name and the
+	// subscription, so they survive restarts
+	stableQueueNaming = "stable"
+)
+
+// subscriptionQueueConfig configures the queues consumed by the subscribers,
+// durable and auto_delete default to the most sensible value of the naming
+type subscriptionQueueConfig struct {
+	Naming     string `json:"naming,omitempty"`
+	Durable    *bool  `json:"durable,omitempty"`
+	AutoDelete *bool  `json:"auto_delete,omitempty"`
+	// Seconds before an undelivered check request is discarded
+	MessageTTL int64 `json:"message_ttl,omitempty"`
+	// Seconds before an unused queue is deleted
+	Expires int64 `json:"expires,omitempty"`
+}
+
+func (c *subscriptionQueueConfig) stable() bool {
+	return c != nil && c.Naming == stableQueueNaming
+}
+
+func (c *subscriptionQueueConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Naming {
+	case "", uniqueQueueNaming, stableQueueNaming:
+	default:
+		return fmt.Errorf("Unknown subscription queue naming: %s", c.Naming)
+	}
+
+	if c.MessageTTL < 0 || c.Expires < 0 {
+		return fmt.Errorf(
+			"Invalid subscription queue TTL: message_ttl %d, expires %d",
+			c.MessageTTL,
+			c.Expires,
+		)
+	}
+
+	return nil
+}
+
+func (c *subscriptionQueueConfig) queueName(client, subscription string) string {
+	if c.stable() {
+		return strings.Join([]string{client, subscription}, "-")
+	}
+
+	return strings.Join(
+		[]string{client, protocolVersion, strconv.Itoa(int(time.Now().Unix()))},
+		"-",
+	)
+}
+
+func (c *subscriptionQueueConfig) options() rabbitmq.QueueOptions {
+	stable := c.stable()
+	options := rabbitmq.QueueOptions{Durable: stable, AutoDelete: !stable}
+
+	if c == nil {
+		return options
+	}
+
+	if c.Durable != nil {
+		options.Durable = *c.Durable
+	}
+
+	if c.AutoDelete != nil {
+		options.AutoDelete = *c.AutoDelete
+	}
+
+	options.MessageTTL = time.Duration(c.MessageTTL) * time.Second
+	options.Expires = time.Duration(c.Expires) * time.Second
+
+	return options
+}
@@ -0,0 +1,117 @@
+package sensu
+
+import (
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/upfluence/goutils/testing/utils"
+	"github.com/upfluence/sensu-client-go/sensu/transport/rabbitmq"
+)
+
+func TestSubscriptionQueueName(t *testing.T) {
+	unique := regexp.MustCompile(`^Test-` + regexp.QuoteMeta(protocolVersion) + `-\d+$`)
+
+	for _, cfg := range []*subscriptionQueueConfig{
+		nil,
+		&subscriptionQueueConfig{},
+		&subscriptionQueueConfig{Naming: uniqueQueueNaming},
+	} {
+		name := newTestConfig(&configPayload{SubscriptionQueue: cfg}).subscriptionQueueName("email")
+
+		if !unique.MatchString(name) {
+			t.Errorf("Expected a unique queue name but got \"%s\" instead!", name)
+		}
+	}
+
+	utils.ValidateStringParameter(
+		newTestConfig(
+			&configPayload{SubscriptionQueue: &subscriptionQueueConfig{Naming: stableQueueNaming}},
+		).subscriptionQueueName("email"),
+		"Test-email",
+		"queue name",
+		t,
+	)
+}
+
+func TestSubscriptionQueueOptions(t *testing.T) {
+	durable, autoDelete := false, true
+
+	for _, tCase := range []struct {
+		cfg      *subscriptionQueueConfig
+		expected rabbitmq.QueueOptions
+	}{
+		{nil, rabbitmq.DefaultQueueOptions},
+		{&subscriptionQueueConfig{}, rabbitmq.DefaultQueueOptions},
+		{
+			&subscriptionQueueConfig{Naming: stableQueueNaming},
+			rabbitmq.QueueOptions{Durable: true},
+		},
+		{
+			&subscriptionQueueConfig{
+				Naming:     stableQueueNaming,
+				Durable:    &durable,
+				AutoDelete: &autoDelete,
+				MessageTTL: 60,
+				Expires:    3600,
+			},
+			rabbitmq.QueueOptions{
+				AutoDelete: true,
+				MessageTTL: time.Minute,
+				Expires:    time.Hour,
+			},
+		},
+	} {
+		options := newTestConfig(
+			&configPayload{SubscriptionQueue: tCase.cfg},
+		).SubscriptionQueueOptions()
+
+		if options != tCase.expected {
+			t.Errorf(
+				"Expected queue options to be %+v but got %+v instead!",
+				tCase.expected,
+				options,
+			)
+		}
+	}
+}
+
+func TestSubscriptionQueueValidation(t *testing.T) {
+	for _, tCase := range []struct {
+		cfg   *subscriptionQueueConfig
+		valid bool
+	}{
+		{nil, true},
+		{&subscriptionQueueConfig{Naming: stableQueueNaming, Expires: 60}, true},
+		{&subscriptionQueueConfig{Naming: "random"}, false},
+		{&subscriptionQueueConfig{MessageTTL: -1}, false},
+	} {
+		if err := tCase.cfg.validate(); (err == nil) != tCase.valid {
+			t.Errorf(
+				"Expected validity of %+v to be %t but got \"%v\" instead!",
+				tCase.cfg,
+				tCase.valid,
+				err,
+			)
+		}
+	}
+}
+
+func TestSubscriptionQueueNamingFromEnvVar(t *testing.T) {
+	os.Setenv("SENSU_SUBSCRIPTION_QUEUE_NAMING", stableQueueNaming)
+	defer os.Unsetenv("SENSU_SUBSCRIPTION_QUEUE_NAMING")
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-noSubs.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(
+		cfg.subscriptionQueueName("email"),
+		"foo-email",
+		"queue name",
+		t,
+	)
+}
@@ -0,0 +1,120 @@
+package sensu
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/upfluence/sensu-client-go/sensu/transport/api"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	"github.com/upfluence/sensu-client-go/sensu/transport/rabbitmq"
+	"github.com/upfluence/sensu-client-go/sensu/transport/redis"
+	"github.com/upfluence/sensu-go/sensu/transport"
+)
+
+const defaultTransportName = "rabbitmq"
+
+// TransportFactory builds a transport from the client configuration
+type TransportFactory func(*Config) (transport.Transport, error)
+
+// TransportStore maps the transport names, selected through the transport
+// name setting, to their factories. Embedding applications can register
+// their own transports.
+var TransportStore = map[string]TransportFactory{
+	"rabbitmq": newRabbitMQTransport,
+	"redis":    newRedisTransport,
+	"http":     newAPITransport,
+	"memory":   newMemoryTransport,
+}
+
+type transportConfig struct {
+	Name string `json:"name,omitempty"`
+	// Failover is the order in which the RabbitMQ brokers are tried,
+	// random or ordered
+	Failover string `json:"failover,omitempty"`
+}
+
+// NewTransport builds the transport selected in the configuration
+func NewTransport(cfg *Config) (transport.Transport, error) {
+	name := cfg.TransportName()
+	factory, ok := TransportStore[name]
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown transport: %s", name)
+	}
+
+	return factory(cfg)
+}
+
+func newRabbitMQTransport(cfg *Config) (transport.Transport, error) {
+	brokers, err := cfg.RabbitMQBrokers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	failover, err := rabbitmq.ParseFailover(cfg.transport().Failover)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := rabbitmq.NewTransportFromBrokers(
+		brokers,
+		cfg.SubscriptionQueueOptions(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.Failover = failover
+
+	return t, nil
+}
+
+// newRedisTransport reads the redis block, the REDIS_URL environment
+// variable takes precedence over it
+func newRedisTransport(cfg *Config) (transport.Transport, error) {
+	config := &redis.Config{}
+
+	if err := cfg.Settings("redis", config); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("REDIS_URL"); v != "" {
+		config.URL = v
+	}
+
+	return redis.NewTransport(config), nil
+}
+
+// newAPITransport reads the api block, the SENSU_API_URL environment
+// variable takes precedence over it
+func newAPITransport(cfg *Config) (transport.Transport, error) {
+	config := &api.Config{}
+
+	if err := cfg.Settings("api", config); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("SENSU_API_URL"); v != "" {
+		config.URL = v
+	}
+
+	t, err := api.NewTransport(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The results are rewritten for the API, so they are signed again
+	if cfg.config != nil && cfg.config.Signing.enabled() {
+		t.Sign = cfg.config.Signing.sign
+	}
+
+	return t, nil
+}
+
+func newMemoryTransport(*Config) (transport.Transport, error) {
+	return memory.NewTransport(), nil
+}
@@ -0,0 +1,421 @@
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/upfluence/goutils/log"
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+)
+
+const (
+	defaultHost          = "127.0.0.1"
+	defaultPort          = 4567
+	defaultResultsPath   = "/results"
+	defaultKeepAlivePath = "/clients"
+	defaultRetries       = 3
+	defaultRetryInterval = time.Second
+	defaultMaxRetryTime  = 30 * time.Second
+	requestTimeout       = 10 * time.Second
+)
+
+var (
+	errNotConnected     = errors.New("The transport is not connected")
+	errInvalidCAFile    = errors.New("No certificate found in the CA file")
+	errInvalidPayload   = errors.New("Result without check")
+	errInvalidKeepAlive = errors.New("The keepalive isn't a JSON object")
+)
+
+// SSLConfig configures the TLS connections to the API
+type SSLConfig struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+func (c *SSLConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errInvalidCAFile
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Config is the api configuration block, like the ruby API settings with
+// the URL taking precedence over the host and the port
+type Config struct {
+	URL      string `json:"url,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Headers are added to every request, e.g. an Authorization token
+	Headers map[string]string `json:"headers,omitempty"`
+	// KeepAlivePath is the endpoint receiving the keepalives, /clients by
+	// default
+	KeepAlivePath string `json:"keepalive_path,omitempty"`
+	// Retries is the number of retries of a failed request, 3 by default
+	Retries *int `json:"retries,omitempty"`
+	// RetryInterval is the delay in seconds before the first retry, doubled
+	// at every retry
+	RetryInterval int `json:"retry_interval,omitempty"`
+	// MaxRetryTime is the time in seconds after which a failed request
+	// isn't retried anymore, 30 by default
+	MaxRetryTime int        `json:"max_retry_time,omitempty"`
+	SSL          *SSLConfig `json:"ssl,omitempty"`
+}
+
+func (c *Config) baseURL() string {
+	if c.URL != "" {
+		return strings.TrimSuffix(c.URL, "/")
+	}
+
+	host, port := defaultHost, defaultPort
+
+	if c.Host != "" {
+		host = c.Host
+	}
+
+	if c.Port != 0 {
+		port = c.Port
+	}
+
+	scheme := "http"
+
+	if c.SSL != nil {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// Transport publishes the results and the keepalives through the Sensu
+// API. It can't receive check requests, so only the standalone checks are
+// executed.
+type Transport struct {
+	Config        *Config
+	Retries       int
+	RetryInterval time.Duration
+	MaxRetryTime  time.Duration
+	// Clock schedules the retries, the real one if nil
+	Clock clock.Clock
+	// Sign signs the /results payloads, which are rebuilt from the results
+	// and lose their signature, nil if they aren't signed
+	Sign func([]byte) ([]byte, error)
+
+	client      *http.Client
+	mu          sync.Mutex
+	connected   bool
+	closingChan chan bool
+	// stopChan is closed by Close to abort the pending retries
+	stopChan chan struct{}
+}
+
+// NewTransport creates a Transport instance from an api configuration, it
+// fails if the TLS files can't be loaded
+func NewTransport(config *Config) (*Transport, error) {
+	t := &Transport{
+		Config:        config,
+		Retries:       defaultRetries,
+		RetryInterval: defaultRetryInterval,
+		MaxRetryTime:  defaultMaxRetryTime,
+		client:        &http.Client{Timeout: requestTimeout},
+		closingChan:   make(chan bool),
+	}
+
+	if config.Retries != nil {
+		t.Retries = *config.Retries
+	}
+
+	if config.RetryInterval > 0 {
+		t.RetryInterval = time.Duration(config.RetryInterval) * time.Second
+	}
+
+	if config.MaxRetryTime > 0 {
+		t.MaxRetryTime = time.Duration(config.MaxRetryTime) * time.Second
+	}
+
+	if config.SSL != nil {
+		tlsConfig, err := config.SSL.tlsConfig()
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid API SSL settings: %s", err.Error())
+		}
+
+		t.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	}
+
+	return t, nil
+}
+
+// Connect doesn't open any connection, the requests are independent
+func (t *Transport) Connect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		t.stopChan = make(chan struct{})
+	}
+
+	t.connected = true
+	log.Noticef("Publishing through the API %s", t.Config.baseURL())
+
+	return nil
+}
+
+func (t *Transport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.connected
+}
+
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.connected {
+		close(t.stopChan)
+	}
+
+	t.connected = false
+
+	return nil
+}
+
+func (t *Transport) GetClosingChan() chan bool {
+	return t.closingChan
+}
+
+func (t *Transport) Publish(_, exchangeName, _ string, message []byte) error {
+	if !t.IsConnected() {
+		return errNotConnected
+	}
+
+	switch exchangeName {
+	case "results":
+		body, err := resultBody(message)
+
+		if err == nil && t.Sign != nil {
+			body, err = t.Sign(body)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return t.post(defaultResultsPath, body)
+	case "keepalives":
+		path := t.Config.KeepAlivePath
+
+		if path == "" {
+			path = defaultKeepAlivePath
+		}
+
+		body, err := keepAliveBody(message)
+
+		if err == nil && t.Sign != nil {
+			body, err = t.Sign(body)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return t.post(path, body)
+	}
+
+	return fmt.Errorf("Unsupported exchange: %s", exchangeName)
+}
+
+// resultBody converts a check result to the /results payload: the check
+// with the client name as source
+func resultBody(message []byte) ([]byte, error) {
+	var result struct {
+		Client string                 `json:"client"`
+		Check  map[string]interface{} `json:"check"`
+	}
+
+	if err := json.Unmarshal(message, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Check == nil {
+		return nil, errInvalidPayload
+	}
+
+	if _, ok := result.Check["source"]; !ok && result.Client != "" {
+		result.Check["source"] = result.Client
+	}
+
+	return json.Marshal(result.Check)
+}
+
+// keepAliveBody sets the keepalives attribute of the client, the API
+// registers the clients posted to /clients without keepalive monitoring
+// otherwise
+func keepAliveBody(message []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+
+	var client map[string]interface{}
+
+	if err := decoder.Decode(&client); err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		return nil, errInvalidKeepAlive
+	}
+
+	if _, ok := client["keepalives"]; !ok {
+		client["keepalives"] = true
+	}
+
+	return json.Marshal(client)
+}
+
+func (t *Transport) clock() clock.Clock {
+	if t.Clock == nil {
+		return clock.Real
+	}
+
+	return t.Clock
+}
+
+func (t *Transport) stopped() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopChan
+}
+
+// post sends the body, the network errors and the server errors are
+// retried with an exponential backoff, until MaxRetryTime is elapsed or the
+// transport is closed
+func (t *Transport) post(path string, body []byte) error {
+	var (
+		clk      = t.clock()
+		deadline = clk.Now().Add(t.MaxRetryTime)
+		interval = t.RetryInterval
+		stop     = t.stopped()
+	)
+
+	for attempt := 0; ; attempt++ {
+		retry, err := t.do(path, body)
+
+		if err == nil || !retry || attempt >= t.Retries {
+			return err
+		}
+
+		if t.MaxRetryTime > 0 && clk.Now().Add(interval).After(deadline) {
+			log.Warningf("Request to %s failed, giving up after %s: %s", path, t.MaxRetryTime, err.Error())
+			return err
+		}
+
+		log.Warningf("Request to %s failed, retrying in %s: %s", path, interval, err.Error())
+
+		timer := clk.NewTimer(interval)
+
+		select {
+		case <-timer.C():
+		case <-stop:
+			timer.Stop()
+			return errNotConnected
+		}
+
+		interval *= 2
+	}
+}
+
+func (t *Transport) do(path string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", t.Config.baseURL()+path, bytes.NewReader(body))
+
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range t.Config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if t.Config.User != "" {
+		req.SetBasicAuth(t.Config.User, t.Config.Password)
+	}
+
+	resp, err := t.client.Do(req)
+
+	if err != nil {
+		return true, err
+	}
+
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500,
+			fmt.Errorf("Unexpected status from %s: %s", path, resp.Status)
+	}
+
+	return false, nil
+}
+
+// Subscribe blocks until the subscription is stopped, the API can't
+// deliver check requests
+func (t *Transport) Subscribe(
+	_,
+	exchangeName,
+	_ string,
+	_ chan []byte,
+	stopChan chan bool,
+) error {
+	log.Warningf("Subscription %s ignored, the API transport can't receive check requests", exchangeName)
+	<-stopChan
+
+	return nil
+}
+
+// Info describes the transport state for the client info endpoint
+func (t *Transport) Info() map[string]interface{} {
+	return map[string]interface{}{"node": t.Config.baseURL()}
+}
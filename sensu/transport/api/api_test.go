@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+)
+
+type request struct {
+	path     string
+	user     string
+	password string
+	token    string
+	body     map[string]interface{}
+}
+
+type dummyAPI struct {
+	mu       sync.Mutex
+	requests []request
+	statuses []int
+}
+
+func (a *dummyAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	req := request{path: r.URL.Path, token: r.Header.Get("X-Token")}
+	req.user, req.password, _ = r.BasicAuth()
+	json.NewDecoder(r.Body).Decode(&req.body)
+	a.requests = append(a.requests, req)
+
+	status := http.StatusAccepted
+
+	if len(a.statuses) > 0 {
+		status, a.statuses = a.statuses[0], a.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func newTestTransport(t *testing.T, config *Config) *Transport {
+	transport, err := NewTransport(config)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	transport.RetryInterval = time.Millisecond
+	transport.Connect()
+
+	return transport
+}
+
+func TestPublishResult(t *testing.T) {
+	api := &dummyAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	transport := newTestTransport(
+		t,
+		&Config{
+			URL:      server.URL,
+			User:     "sensu",
+			Password: "secret",
+			Headers:  map[string]string{"X-Token": "foo"},
+		},
+	)
+
+	err := transport.Publish(
+		"direct",
+		"results",
+		"",
+		[]byte(`{"client":"foo","check":{"name":"disk","status":1,"output":"bar"}}`),
+	)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	expected := []request{
+		{
+			"/results",
+			"sensu",
+			"secret",
+			"foo",
+			map[string]interface{}{
+				"name":   "disk",
+				"status": 1.0,
+				"output": "bar",
+				"source": "foo",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("Expected requests to be %+v but got %+v instead!", expected, api.requests)
+	}
+}
+
+func TestPublishKeepAlive(t *testing.T) {
+	api := &dummyAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	transport := newTestTransport(
+		t,
+		&Config{URL: server.URL, KeepAlivePath: "/keepalives"},
+	)
+
+	if err := transport.Publish("direct", "keepalives", "", []byte(`{"name":"foo"}`)); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if len(api.requests) != 1 || api.requests[0].path != "/keepalives" {
+		t.Fatalf("Expected a request to /keepalives but got %+v instead!", api.requests)
+	}
+
+	if v := api.requests[0].body["keepalives"]; v != true {
+		t.Errorf("Expected the keepalives attribute to be true but got %v instead!", v)
+	}
+
+	if err := transport.Publish("fanout", "email", "", []byte(`{}`)); err == nil {
+		t.Error("Expected an error for an unsupported exchange but got nil instead!")
+	}
+
+	transport.Close()
+
+	if err := transport.Publish("direct", "keepalives", "", []byte(`{}`)); err != errNotConnected {
+		t.Errorf("Expected error to be \"%s\" but got \"%v\" instead!", errNotConnected, err)
+	}
+}
+
+func TestPublishRetry(t *testing.T) {
+	for _, tCase := range []struct {
+		statuses []int
+		requests int
+		success  bool
+	}{
+		{[]int{503, 502}, 3, true},
+		{[]int{503, 503, 503, 503}, 4, false},
+		{[]int{400}, 1, false},
+	} {
+		api := &dummyAPI{statuses: tCase.statuses}
+		server := httptest.NewServer(api)
+
+		err := newTestTransport(t, &Config{URL: server.URL}).
+			Publish("direct", "keepalives", "", []byte(`{"name":"foo"}`))
+
+		if (err == nil) != tCase.success {
+			t.Errorf("Expected success to be %t but got \"%v\" instead!", tCase.success, err)
+		}
+
+		if len(api.requests) != tCase.requests {
+			t.Errorf(
+				"Expected %d requests but got %d instead!",
+				tCase.requests,
+				len(api.requests),
+			)
+		}
+
+		server.Close()
+	}
+}
+
+func TestPublishTLS(t *testing.T) {
+	api := &dummyAPI{}
+	server := httptest.NewTLSServer(api)
+	defer server.Close()
+
+	if err := newTestTransport(t, &Config{URL: server.URL}).
+		Publish("direct", "keepalives", "", []byte(`{}`)); err == nil {
+		t.Error("Expected an error for an unknown authority but got nil instead!")
+	}
+
+	caFile, err := ioutil.TempFile("", "sensu-ca")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer os.Remove(caFile.Name())
+
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	caFile.Close()
+
+	transport := newTestTransport(
+		t,
+		&Config{URL: server.URL, SSL: &SSLConfig{CAFile: caFile.Name()}},
+	)
+
+	if err := transport.Publish("direct", "keepalives", "", []byte(`{}`)); err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if _, err := NewTransport(&Config{SSL: &SSLConfig{CAFile: "/nonexistent"}}); err == nil {
+		t.Error("Expected an error for a missing CA file but got nil instead!")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	transport := newTestTransport(t, &Config{})
+	stopChan, done := make(chan bool), make(chan error)
+
+	go func() {
+		done <- transport.Subscribe("#", "email", "", make(chan []byte), stopChan)
+	}()
+
+	stopChan <- true
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+}
+
+func TestConfigBaseURL(t *testing.T) {
+	for _, tCase := range []struct {
+		config   Config
+		expected string
+	}{
+		{Config{}, "http://127.0.0.1:4567"},
+		{Config{Host: "api", Port: 8080, SSL: &SSLConfig{}}, "https://api:8080"},
+		{Config{URL: "https://sensu.example.com/api/"}, "https://sensu.example.com/api"},
+	} {
+		if url := tCase.config.baseURL(); url != tCase.expected {
+			t.Errorf("Expected URL to be %s but got %s instead!", tCase.expected, url)
+		}
+	}
+}
+
+func (a *dummyAPI) requestCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.requests)
+}
+
+func TestPublishMaxRetryTime(t *testing.T) {
+	api := &dummyAPI{statuses: []int{503, 503, 503, 503}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	retries := 10
+	fake := clock.NewFake(time.Unix(1479057736, 0))
+	transport := newTestTransport(
+		t,
+		&Config{URL: server.URL, Retries: &retries, RetryInterval: 1, MaxRetryTime: 4},
+	)
+	transport.RetryInterval = time.Second
+	transport.Clock = fake
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- transport.Publish("direct", "keepalives", "", []byte(`{"name":"foo"}`))
+	}()
+
+	// The retries wait 1s then 2s, the third one would end after 4s
+	for _, interval := range []time.Duration{time.Second, 2 * time.Second} {
+		for fake.Timers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		fake.Advance(interval)
+	}
+
+	if err := <-done; err == nil {
+		t.Error("Expected an error after the max retry time but got nil instead!")
+	}
+
+	if n := api.requestCount(); n != 3 {
+		t.Errorf("Expected 3 requests but got %d instead!", n)
+	}
+}
+
+func TestCloseStopsRetries(t *testing.T) {
+	api := &dummyAPI{statuses: []int{503}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	transport := newTestTransport(t, &Config{URL: server.URL})
+	transport.RetryInterval = time.Hour
+	transport.MaxRetryTime = 2 * time.Hour
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- transport.Publish("direct", "keepalives", "", []byte(`{"name":"foo"}`))
+	}()
+
+	for api.requestCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	transport.Close()
+
+	select {
+	case err := <-done:
+		if err != errNotConnected {
+			t.Errorf("Expected error to be \"%s\" but got \"%v\" instead!", errNotConnected, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the retry to be aborted by Close!")
+	}
+}
@@ -0,0 +1,358 @@
+package memory
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+)
+
+const queueSize = 1024
+
+var (
+	errNotConnected = errors.New("The transport is not connected")
+	// ErrInjectedFailure is returned by the operations failed on purpose
+	ErrInjectedFailure = errors.New("Injected failure")
+)
+
+// Message is a message published through the transport
+type Message struct {
+	ExchangeType string
+	ExchangeName string
+	Key          string
+	Body         []byte
+}
+
+type binding struct {
+	queue string
+	key   string
+}
+
+// Transport is an in-process transport routing the published messages to
+// the queues bound to the exchange with a matching key, like RabbitMQ does.
+// It records the traffic and lets tests inject disconnections, failures
+// and latency. It is meant for tests and for embedding the client in
+// applications without a broker.
+type Transport struct {
+	// Clock times the latency, the real clock is used if nil
+	Clock clock.Clock
+
+	mu          sync.Mutex
+	connected   bool
+	closeChan   chan bool
+	closingChan chan bool
+	queues      map[string]chan []byte
+	bindings    map[string][]binding
+	subscribers map[string]int
+	published   []Message
+
+	failedConnects  int
+	failedPublishes int
+	failure         error
+	latency         time.Duration
+}
+
+// NewTransport creates a disconnected Transport instance
+func NewTransport() *Transport {
+	return &Transport{
+		closingChan: make(chan bool, 1),
+		queues:      make(map[string]chan []byte),
+		bindings:    make(map[string][]binding),
+		subscribers: make(map[string]int),
+	}
+}
+
+func (t *Transport) Connect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failedConnects > 0 {
+		t.failedConnects--
+		return t.failure
+	}
+
+	if !t.connected {
+		t.connected = true
+		t.closeChan = make(chan bool)
+	}
+
+	return nil
+}
+
+func (t *Transport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.connected
+}
+
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Discard the disconnection notified before the close
+	select {
+	case <-t.closingChan:
+	default:
+	}
+
+	t.disconnect()
+
+	return nil
+}
+
+func (t *Transport) disconnect() {
+	if t.connected {
+		t.connected = false
+		close(t.closeChan)
+	}
+}
+
+// Disconnect simulates a connection loss: the subscriptions are stopped
+// and the client is notified through the closing channel
+func (t *Transport) Disconnect() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return
+	}
+
+	t.disconnect()
+
+	select {
+	case t.closingChan <- true:
+	default:
+	}
+}
+
+func (t *Transport) GetClosingChan() chan bool {
+	return t.closingChan
+}
+
+// FailConnects makes the next n connections fail with err,
+// ErrInjectedFailure if nil
+func (t *Transport) FailConnects(n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.failedConnects = n
+	t.failure = injectedFailure(err)
+}
+
+// FailPublishes makes the next n publications fail with err,
+// ErrInjectedFailure if nil
+func (t *Transport) FailPublishes(n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.failedPublishes = n
+	t.failure = injectedFailure(err)
+}
+
+func injectedFailure(err error) error {
+	if err == nil {
+		return ErrInjectedFailure
+	}
+
+	return err
+}
+
+func (t *Transport) clock() clock.Clock {
+	if t.Clock == nil {
+		return clock.Real
+	}
+
+	return t.Clock
+}
+
+// SetLatency delays every publication by d, on the transport clock
+func (t *Transport) SetLatency(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.latency = d
+}
+
+func (t *Transport) Publish(exchangeType, exchangeName, key string, message []byte) error {
+	t.mu.Lock()
+	latency := t.latency
+	t.mu.Unlock()
+
+	if latency > 0 {
+		timer := t.clock().NewTimer(latency)
+		<-timer.C()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return errNotConnected
+	}
+
+	if t.failedPublishes > 0 {
+		t.failedPublishes--
+		return t.failure
+	}
+
+	t.published = append(
+		t.published,
+		Message{exchangeType, exchangeName, key, message},
+	)
+
+	for _, b := range t.bindings[exchangeName] {
+		if exchangeType != "fanout" && !matchKey(b.key, key) {
+			continue
+		}
+
+		select {
+		case t.queues[b.queue] <- message:
+		default:
+			// The queue is full, the message is dropped like an overflowing
+			// RabbitMQ queue would do
+		}
+	}
+
+	return nil
+}
+
+// matchKey matches a routing key against a binding key, following the
+// topic exchange rules: * matches a word and # zero or more words
+func matchKey(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	}
+
+	return len(words) > 0 && pattern[0] == words[0] &&
+		matchWords(pattern[1:], words[1:])
+}
+
+func (t *Transport) Subscribe(
+	key,
+	exchangeName,
+	queueName string,
+	messageChan chan []byte,
+	stopChan chan bool,
+) error {
+	queue, closeChan, err := t.bind(key, exchangeName, queueName)
+
+	if err != nil {
+		return err
+	}
+
+	defer t.unsubscribe(exchangeName)
+
+	for {
+		select {
+		case message := <-queue:
+			select {
+			case messageChan <- message:
+			case <-stopChan:
+				return nil
+			case <-closeChan:
+				return nil
+			}
+		case <-stopChan:
+			return nil
+		case <-closeChan:
+			return nil
+		}
+	}
+}
+
+// bind declares the queue and binds it to the exchange
+func (t *Transport) bind(key, exchangeName, queueName string) (chan []byte, chan bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return nil, nil, errNotConnected
+	}
+
+	queue, ok := t.queues[queueName]
+
+	if !ok {
+		queue = make(chan []byte, queueSize)
+		t.queues[queueName] = queue
+	}
+
+	t.subscribers[exchangeName]++
+
+	b := binding{queueName, key}
+
+	for _, existing := range t.bindings[exchangeName] {
+		if existing == b {
+			return queue, t.closeChan, nil
+		}
+	}
+
+	t.bindings[exchangeName] = append(t.bindings[exchangeName], b)
+
+	return queue, t.closeChan, nil
+}
+
+func (t *Transport) unsubscribe(exchangeName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.subscribers[exchangeName]--
+}
+
+// Subscribers returns the number of active subscriptions to the exchange
+func (t *Transport) Subscribers(exchangeName string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.subscribers[exchangeName]
+}
+
+// Messages returns all the messages published successfully, in
+// publication order
+func (t *Transport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Message{}, t.published...)
+}
+
+// Published returns the messages published to the given exchange, in
+// publication order
+func (t *Transport) Published(exchangeName string) []Message {
+	messages := []Message{}
+
+	for _, message := range t.Messages() {
+		if message.ExchangeName == exchangeName {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets the recorded messages
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.published = nil
+}
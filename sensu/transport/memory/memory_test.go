@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/upfluence/sensu-client-go/sensu/clock"
+)
+
+type subscription struct {
+	messageChan chan []byte
+	stopChan    chan bool
+	done        chan error
+}
+
+func subscribe(t *testing.T, transport *Transport, key, exchangeName, queueName string) *subscription {
+	s := &subscription{make(chan []byte), make(chan bool), make(chan error, 1)}
+	subscribers := transport.Subscribers(exchangeName)
+
+	go func() {
+		s.done <- transport.Subscribe(key, exchangeName, queueName, s.messageChan, s.stopChan)
+	}()
+
+	for i := 0; transport.Subscribers(exchangeName) == subscribers; i++ {
+		if i == 1000 {
+			t.Fatalf("Expected the %s queue to be bound!", queueName)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return s
+}
+
+func (s *subscription) receive(t *testing.T, expected string) {
+	select {
+	case message := <-s.messageChan:
+		if string(message) != expected {
+			t.Errorf("Expected message to be \"%s\" but got \"%s\" instead!", expected, message)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected message \"%s\" but got nothing!", expected)
+	}
+}
+
+func (s *subscription) nothing(t *testing.T) {
+	select {
+	case message := <-s.messageChan:
+		t.Errorf("Expected no message but got \"%s\" instead!", message)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPublishNotConnected(t *testing.T) {
+	if err := NewTransport().Publish("direct", "results", "", []byte("foo")); err == nil {
+		t.Error("Expected an error but got nil instead!")
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	transport := NewTransport()
+	transport.Connect()
+
+	s := subscribe(t, transport, "#", "email", "foo-email")
+
+	if err := transport.Publish("fanout", "email", "", []byte("foo")); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	transport.Publish("fanout", "slack", "", []byte("bar"))
+
+	s.receive(t, "foo")
+
+	if messages := transport.Published("slack"); len(messages) != 1 {
+		t.Errorf("Expected 1 message to be published but got %d instead!", len(messages))
+	}
+
+	if messages := transport.Messages(); len(messages) != 2 {
+		t.Errorf("Expected 2 messages to be recorded but got %d instead!", len(messages))
+	}
+
+	transport.Close()
+
+	if err := <-s.done; err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if transport.IsConnected() {
+		t.Error("Expected the transport to be disconnected!")
+	}
+
+	if n := transport.Subscribers("email"); n != 0 {
+		t.Errorf("Expected no subscriber but got %d instead!", n)
+	}
+}
+
+func TestPublishRouting(t *testing.T) {
+	transport := NewTransport()
+	transport.Connect()
+	defer transport.Close()
+
+	checks := subscribe(t, transport, "checks.*", "events", "checks")
+	all := subscribe(t, transport, "#", "events", "all")
+	disk := subscribe(t, transport, "checks.disk", "events", "disk")
+
+	transport.Publish("topic", "events", "checks.disk", []byte("disk"))
+	checks.receive(t, "disk")
+	all.receive(t, "disk")
+	disk.receive(t, "disk")
+
+	transport.Publish("topic", "events", "checks.cpu", []byte("cpu"))
+	checks.receive(t, "cpu")
+	all.receive(t, "cpu")
+	disk.nothing(t)
+
+	transport.Publish("topic", "events", "metrics.cpu.load", []byte("load"))
+	all.receive(t, "load")
+	checks.nothing(t)
+	disk.nothing(t)
+}
+
+func TestMatchKey(t *testing.T) {
+	for _, tCase := range []struct {
+		pattern, key string
+		expected     bool
+	}{
+		{"#", "", true},
+		{"#", "foo.bar", true},
+		{"foo.#", "foo", true},
+		{"foo.#", "foo.bar.baz", true},
+		{"foo.*", "foo", false},
+		{"foo.*", "foo.bar", true},
+		{"*.bar", "foo.bar", true},
+		{"foo.bar", "foo.baz", false},
+		{"", "", true},
+	} {
+		if match := matchKey(tCase.pattern, tCase.key); match != tCase.expected {
+			t.Errorf(
+				"Expected match of %s with %s to be %t but got %t instead!",
+				tCase.pattern,
+				tCase.key,
+				tCase.expected,
+				match,
+			)
+		}
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	transport := NewTransport()
+	transport.Connect()
+
+	s := subscribe(t, transport, "#", "email", "foo-email")
+
+	transport.Disconnect()
+
+	select {
+	case <-transport.GetClosingChan():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the disconnection to be notified!")
+	}
+
+	if err := <-s.done; err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if err := transport.Publish("fanout", "email", "", []byte("foo")); err != errNotConnected {
+		t.Errorf("Expected error to be \"%s\" but got \"%v\" instead!", errNotConnected, err)
+	}
+
+	// The messages published while disconnected are kept by the queue
+	transport.Connect()
+	transport.Publish("fanout", "email", "", []byte("foo"))
+	s = subscribe(t, transport, "#", "email", "foo-email")
+	s.receive(t, "foo")
+}
+
+func TestFaultInjection(t *testing.T) {
+	transport := NewTransport()
+	transport.FailConnects(1, nil)
+
+	if err := transport.Connect(); err != ErrInjectedFailure {
+		t.Errorf("Expected error to be \"%s\" but got \"%v\" instead!", ErrInjectedFailure, err)
+	}
+
+	if err := transport.Connect(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	transport.FailPublishes(2, nil)
+
+	for i := 0; i < 3; i++ {
+		transport.Publish("direct", "results", "", []byte("foo"))
+	}
+
+	if messages := transport.Published("results"); len(messages) != 1 {
+		t.Errorf("Expected 1 message to be published but got %d instead!", len(messages))
+	}
+
+	clk := clock.NewFake(time.Unix(1479057736, 0))
+	done := make(chan error, 1)
+
+	transport.Reset()
+	transport.Clock = clk
+	transport.SetLatency(20 * time.Millisecond)
+
+	go func() { done <- transport.Publish("direct", "results", "", []byte("foo")) }()
+
+	for i := 0; clk.Timers() == 0; i++ {
+		if i == 1000 {
+			t.Fatal("Expected the publication to wait for the latency!")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if messages := transport.Messages(); len(messages) != 0 {
+		t.Errorf("Expected no message before the latency but got %d instead!", len(messages))
+	}
+
+	clk.Advance(20 * time.Millisecond)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if messages := transport.Messages(); len(messages) != 1 {
+		t.Errorf("Expected 1 message to be recorded but got %d instead!", len(messages))
+	}
+}
@@ -0,0 +1,200 @@
+package rabbitmq
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	stdRabbitMQ "github.com/upfluence/sensu-go/sensu/transport/rabbitmq"
+)
+
+const (
+	brokerRetryInterval    = 10 * time.Second
+	maxBrokerRetryInterval = 5 * time.Minute
+)
+
+// Failover is the order in which the brokers of a cluster are tried
+type Failover string
+
+const (
+	// RandomFailover tries the brokers in a random order, like the ruby client
+	RandomFailover Failover = "random"
+	// OrderedFailover tries the brokers in the configuration order
+	OrderedFailover Failover = "ordered"
+)
+
+// ParseFailover validates a failover name, the empty name is the random one
+func ParseFailover(name string) (Failover, error) {
+	switch f := Failover(name); f {
+	case "":
+		return RandomFailover, nil
+	case RandomFailover, OrderedFailover:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("Unknown failover: %s", name)
+}
+
+// BrokerStatus reports the health of a broker of the cluster
+type BrokerStatus struct {
+	Node        string     `json:"node"`
+	Active      bool       `json:"active"`
+	Healthy     bool       `json:"healthy"`
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+}
+
+// broker tracks the consecutive connection failures of a broker, a broker
+// which failed recently is tried after the healthy ones
+type broker struct {
+	config      *stdRabbitMQ.TransportConfig
+	tls         *tls.Config
+	failures    int
+	lastError   error
+	lastFailure time.Time
+}
+
+// node identifies the broker without leaking its credentials
+func (b *broker) node() string {
+	return net.JoinHostPort(b.config.Host, b.config.Port.String())
+}
+
+func (b *broker) healthy(now time.Time) bool {
+	if b.failures == 0 {
+		return true
+	}
+
+	retryInterval := time.Duration(b.failures) * brokerRetryInterval
+
+	if retryInterval > maxBrokerRetryInterval {
+		retryInterval = maxBrokerRetryInterval
+	}
+
+	return now.Sub(b.lastFailure) >= retryInterval
+}
+
+func (b *broker) fail(err error, now time.Time) {
+	b.failures++
+	b.lastError = err
+	b.lastFailure = now
+}
+
+func (b *broker) succeed() {
+	b.failures = 0
+	b.lastError = nil
+}
+
+// brokerPool holds the brokers of the cluster and the active one
+type brokerPool struct {
+	mu      sync.Mutex
+	brokers []*broker
+	active  *broker
+	now     func() time.Time
+}
+
+func newBrokerPool(configs []*stdRabbitMQ.TransportConfig) *brokerPool {
+	brokers := make([]*broker, len(configs))
+
+	for i, config := range configs {
+		brokers[i] = &broker{config: config}
+	}
+
+	return &brokerPool{brokers: brokers, now: time.Now}
+}
+
+// candidates returns the brokers to try: the healthy ones first, then the
+// ones which failed recently, each group following the failover order
+func (p *brokerPool) candidates(failover Failover) []*broker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ordered := make([]*broker, len(p.brokers))
+
+	if failover == OrderedFailover {
+		copy(ordered, p.brokers)
+	} else {
+		for i, idx := range rand.Perm(len(p.brokers)) {
+			ordered[i] = p.brokers[idx]
+		}
+	}
+
+	var healthy, unhealthy []*broker
+	now := p.now()
+
+	for _, b := range ordered {
+		if b.healthy(now) {
+			healthy = append(healthy, b)
+		} else {
+			unhealthy = append(unhealthy, b)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *brokerPool) fail(b *broker, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.fail(err, p.now())
+
+	if p.active == b {
+		p.active = nil
+	}
+}
+
+func (p *brokerPool) activate(b *broker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.succeed()
+	p.active = b
+}
+
+func (p *brokerPool) deactivate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active = nil
+}
+
+func (p *brokerPool) activeNode() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active == nil {
+		return ""
+	}
+
+	return p.active.node()
+}
+
+func (p *brokerPool) statuses() []BrokerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]BrokerStatus, len(p.brokers))
+	now := p.now()
+
+	for i, b := range p.brokers {
+		statuses[i] = BrokerStatus{
+			Node:     b.node(),
+			Active:   b == p.active,
+			Healthy:  b.healthy(now),
+			Failures: b.failures,
+		}
+
+		if b.lastError != nil {
+			lastFailure := b.lastFailure
+
+			statuses[i].LastError = b.lastError.Error()
+			statuses[i].LastFailure = &lastFailure
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,316 @@
+package rabbitmq
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/upfluence/goutils/log"
+	stdRabbitMQ "github.com/upfluence/sensu-go/sensu/transport/rabbitmq"
+)
+
+const (
+	defaultHeartbeat = 10 * time.Second
+	defaultLocale    = "en_US"
+)
+
+var (
+	errChannelNotOpened = errors.New("The channel is not opened")
+	errNoBroker         = errors.New("No RabbitMQ broker configured")
+)
+
+// QueueOptions configures the declaration of the subscription queues
+type QueueOptions struct {
+	Durable    bool
+	AutoDelete bool
+	// Discards the messages older than MessageTTL, zero means no TTL
+	MessageTTL time.Duration
+	// Deletes the queue once unused for Expires, zero means never
+	Expires time.Duration
+}
+
+// DefaultQueueOptions are the ruby client ones: the queues are transient and
+// deleted with their last consumer
+var DefaultQueueOptions = QueueOptions{AutoDelete: true}
+
+func (o QueueOptions) arguments() amqp.Table {
+	args := amqp.Table{}
+
+	if o.MessageTTL > 0 {
+		args["x-message-ttl"] = int64(o.MessageTTL / time.Millisecond)
+	}
+
+	if o.Expires > 0 {
+		args["x-expires"] = int64(o.Expires / time.Millisecond)
+	}
+
+	return args
+}
+
+// Transport extends the sensu-go RabbitMQ transport to declare the
+// subscription queues with the given options and to fail over between the
+// brokers of a cluster, following the Failover order and their health
+type Transport struct {
+	*stdRabbitMQ.RabbitMQTransport
+	QueueOptions QueueOptions
+	Failover     Failover
+
+	pool   *brokerPool
+	dialer func(string, amqp.Config) (stdRabbitMQ.AMQPConnection, error)
+}
+
+// NewTransportFromURI creates a Transport instance from a given URI
+func NewTransportFromURI(uri string, options QueueOptions) (*Transport, error) {
+	t, err := stdRabbitMQ.NewRabbitMQTransport(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransport(t, options), nil
+}
+
+// NewTransport creates a Transport instance from a list of TransportConfig
+// objects in order to connect to a High Availability RabbitMQ cluster
+func NewTransport(
+	configs []*stdRabbitMQ.TransportConfig,
+	options QueueOptions,
+) *Transport {
+	return newTransport(stdRabbitMQ.NewRabbitMQHATransport(configs), options)
+}
+
+// NewTransportFromBrokers creates a Transport instance connecting to the
+// brokers over TLS when they have a ssl block, it fails if the certificates
+// can't be loaded
+func NewTransportFromBrokers(
+	brokers []*BrokerConfig,
+	options QueueOptions,
+) (*Transport, error) {
+	configs := make([]*stdRabbitMQ.TransportConfig, len(brokers))
+
+	for i, b := range brokers {
+		configs[i] = b.TransportConfig
+	}
+
+	t := NewTransport(configs, options)
+
+	for i, b := range brokers {
+		if b.SSL == nil {
+			continue
+		}
+
+		config, err := b.SSL.TLSConfig(time.Now())
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.pool.brokers[i].tls = config
+	}
+
+	return t, nil
+}
+
+func newTransport(
+	t *stdRabbitMQ.RabbitMQTransport,
+	options QueueOptions,
+) *Transport {
+	return &Transport{
+		RabbitMQTransport: t,
+		QueueOptions:      options,
+		Failover:          RandomFailover,
+		pool:              newBrokerPool(t.Configs),
+		dialer:            dial,
+	}
+}
+
+func dial(uri string, config amqp.Config) (stdRabbitMQ.AMQPConnection, error) {
+	conn, err := amqp.DialConfig(uri, config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &stdRabbitMQ.Connection{Connection: conn}, nil
+}
+
+// Connect tries the brokers until one accepts the connection, the healthy
+// brokers are tried first
+func (t *Transport) Connect() error {
+	err := errNoBroker
+
+	for _, b := range t.pool.candidates(t.Failover) {
+		if err = t.connect(b); err != nil {
+			log.Warningf("Failed to connect to %s: %s", b.node(), err.Error())
+			t.pool.fail(b, err)
+			continue
+		}
+
+		t.pool.activate(b)
+		log.Noticef("RabbitMQ connection and channel opened to %s", b.node())
+
+		return nil
+	}
+
+	log.Errorf("RabbitMQ connection error: %s", err.Error())
+
+	return err
+}
+
+func (t *Transport) connect(b *broker) error {
+	config := amqp.Config{Heartbeat: defaultHeartbeat, Locale: defaultLocale}
+
+	if v := b.config.Heartbeat.String(); v != "" {
+		// The heartbeat is a number of seconds, fractional values included
+		if heartbeat, err := time.ParseDuration(v + "s"); err != nil {
+			log.Warningf("Failed to parse the heartbeat value \"%s\": %s", v, err.Error())
+			config.Heartbeat = 0
+		} else {
+			config.Heartbeat = heartbeat
+		}
+	}
+
+	uri := b.config.GetURI()
+
+	if b.tls != nil {
+		uri = "amqps" + strings.TrimPrefix(uri, "amqp")
+		config.TLSClientConfig = b.tls
+	}
+
+	log.Noticef("Trying to connect to %s", b.node())
+
+	conn, err := t.dialer(uri, config)
+
+	if err != nil {
+		return err
+	}
+
+	channel, err := conn.Channel()
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if v := b.config.Prefetch.String(); v != "" {
+		if prefetch, err := strconv.Atoi(v); err != nil {
+			log.Warningf("Failed to parse the prefetch value \"%s\": %s", v, err.Error())
+		} else if err := channel.Qos(prefetch, 0, false); err != nil {
+			log.Warningf("Failed to set the prefetch value: %s", err.Error())
+		}
+	}
+
+	t.Connection, t.Channel = conn, channel
+
+	closeChan := make(chan *amqp.Error)
+	channel.NotifyClose(closeChan)
+
+	go func() {
+		// The channel is closed without error on a graceful close
+		if err, ok := <-closeChan; ok {
+			log.Warningf("Connection to %s lost: %s", b.node(), err.Error())
+			t.pool.fail(b, err)
+			t.ClosingChannel <- true
+		}
+	}()
+
+	return nil
+}
+
+func (t *Transport) Close() error {
+	t.pool.deactivate()
+
+	return t.RabbitMQTransport.Close()
+}
+
+// ActiveNode returns the broker the transport is connected to, empty if it
+// is disconnected
+func (t *Transport) ActiveNode() string {
+	return t.pool.activeNode()
+}
+
+// Brokers reports the health of every broker of the cluster
+func (t *Transport) Brokers() []BrokerStatus {
+	return t.pool.statuses()
+}
+
+// Info describes the transport state for the client info endpoint
+func (t *Transport) Info() map[string]interface{} {
+	return map[string]interface{}{
+		"node":    t.ActiveNode(),
+		"brokers": t.Brokers(),
+	}
+}
+
+func (t *Transport) Subscribe(
+	key,
+	exchangeName,
+	queueName string,
+	messageChan chan []byte,
+	stopChan chan bool,
+) error {
+	if t.Channel == nil {
+		return errChannelNotOpened
+	}
+
+	if err := t.Channel.ExchangeDeclare(
+		exchangeName,
+		"fanout",
+		false,
+		false,
+		false,
+		false,
+		amqp.Table{},
+	); err != nil {
+		log.Errorf("Can't declare the exchange: %s", err.Error())
+		return err
+	}
+
+	log.Infof("Exchange %s declared", exchangeName)
+
+	if _, err := t.Channel.QueueDeclare(
+		queueName,
+		t.QueueOptions.Durable,
+		t.QueueOptions.AutoDelete,
+		false,
+		false,
+		t.QueueOptions.arguments(),
+	); err != nil {
+		log.Errorf("Can't declare the queue: %s", err.Error())
+		return err
+	}
+
+	log.Infof("Queue %s declared", queueName)
+
+	if err := t.Channel.QueueBind(queueName, key, exchangeName, false, nil); err != nil {
+		log.Errorf("Can't bind the queue: %s", err.Error())
+		return err
+	}
+
+	log.Noticef("Queue %s binded to %s for key %s", queueName, exchangeName, key)
+
+	deliveries, err := t.Channel.Consume(queueName, "", true, false, false, false, nil)
+
+	if err != nil {
+		log.Errorf("Can't consume the queue: %s", err.Error())
+		return err
+	}
+
+	log.Infof("Consuming the queue %s", queueName)
+
+	for {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				t.ClosingChannel <- true
+				return nil
+			}
+
+			messageChan <- delivery.Body
+		case <-stopChan:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package rabbitmq
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	stdRabbitMQ "github.com/upfluence/sensu-go/sensu/transport/rabbitmq"
+)
+
+type queueDeclaration struct {
+	name                string
+	durable, autoDelete bool
+	args                amqp.Table
+}
+
+type dummyChannel struct {
+	declarations []queueDeclaration
+	deliveries   chan amqp.Delivery
+}
+
+func (c *dummyChannel) Consume(
+	string, string, bool, bool, bool, bool, amqp.Table,
+) (<-chan amqp.Delivery, error) {
+	return c.deliveries, nil
+}
+
+func (c *dummyChannel) ExchangeDeclare(
+	string, string, bool, bool, bool, bool, amqp.Table,
+) error {
+	return nil
+}
+
+func (c *dummyChannel) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	return ch
+}
+
+func (c *dummyChannel) Publish(string, string, bool, bool, amqp.Publishing) error {
+	return nil
+}
+
+func (c *dummyChannel) Qos(int, int, bool) error {
+	return nil
+}
+
+func (c *dummyChannel) QueueBind(string, string, string, bool, amqp.Table) error {
+	return nil
+}
+
+func (c *dummyChannel) QueueDeclare(
+	name string,
+	durable, autoDelete, _, _ bool,
+	args amqp.Table,
+) (amqp.Queue, error) {
+	c.declarations = append(
+		c.declarations,
+		queueDeclaration{name, durable, autoDelete, args},
+	)
+
+	return amqp.Queue{Name: name}, nil
+}
+
+func TestSubscribeQueueOptions(t *testing.T) {
+	for _, tCase := range []struct {
+		options  QueueOptions
+		expected queueDeclaration
+	}{
+		{
+			DefaultQueueOptions,
+			queueDeclaration{"foo", false, true, amqp.Table{}},
+		},
+		{
+			QueueOptions{
+				Durable:    true,
+				MessageTTL: time.Minute,
+				Expires:    24 * time.Hour,
+			},
+			queueDeclaration{
+				"foo",
+				true,
+				false,
+				amqp.Table{"x-message-ttl": int64(60000), "x-expires": int64(86400000)},
+			},
+		},
+	} {
+		channel := &dummyChannel{deliveries: make(chan amqp.Delivery, 1)}
+		transport := NewTransport([]*stdRabbitMQ.TransportConfig{}, tCase.options)
+		transport.Channel = channel
+
+		msgChan, stopChan := make(chan []byte), make(chan bool)
+		channel.deliveries <- amqp.Delivery{Body: []byte("bar")}
+
+		go transport.Subscribe("#", "subscription", "foo", msgChan, stopChan)
+
+		if msg := <-msgChan; string(msg) != "bar" {
+			t.Errorf("Expected message to be \"bar\" but got \"%s\" instead!", msg)
+		}
+
+		stopChan <- true
+
+		if !reflect.DeepEqual(channel.declarations, []queueDeclaration{tCase.expected}) {
+			t.Errorf(
+				"Expected queue declarations to be %+v but got %+v instead!",
+				tCase.expected,
+				channel.declarations,
+			)
+		}
+	}
+}
+
+func TestSubscribeNotConnected(t *testing.T) {
+	err := NewTransport([]*stdRabbitMQ.TransportConfig{}, DefaultQueueOptions).
+		Subscribe("#", "subscription", "foo", nil, nil)
+
+	if err != errChannelNotOpened {
+		t.Errorf(
+			"Expected error to be \"%s\" but got \"%v\" instead!",
+			errChannelNotOpened,
+			err,
+		)
+	}
+}
@@ -0,0 +1,199 @@
+package rabbitmq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/upfluence/goutils/log"
+	stdRabbitMQ "github.com/upfluence/sensu-go/sensu/transport/rabbitmq"
+)
+
+// expiryWarning is how long before their expiry the certificates are
+// reported as expiring soon
+const expiryWarning = 30 * 24 * time.Hour
+
+var (
+	errInvalidCAFile  = errors.New("No certificate found in the CA file")
+	errMissingKeyFile = errors.New("The private key file is required with the cert chain file")
+	errMissingCert    = errors.New("The cert chain file is required with the private key file")
+)
+
+// SSLConfig is the ssl block of a broker, the ruby client one extended with
+// the CA and the server verification settings
+type SSLConfig struct {
+	CertChainFile  string `json:"cert_chain_file,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	CAFile         string `json:"ca_file,omitempty"`
+	// Verifies the server certificate against this name instead of the host
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// TLSConfig loads the certificates, it fails if one of them can't be read
+// or isn't valid at the given time. The invalid CAs of a bundle are skipped
+// as long as one of them is valid.
+func (c *SSLConfig) TLSConfig(now time.Time) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadCAFile(c.CAFile, now)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	switch {
+	case c.CertChainFile != "" && c.PrivateKeyFile == "":
+		return nil, errMissingKeyFile
+	case c.CertChainFile == "" && c.PrivateKeyFile != "":
+		return nil, errMissingCert
+	case c.CertChainFile != "":
+		cert, err := tls.LoadX509KeyPair(c.CertChainFile, c.PrivateKeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Failed to load the certificate %s: %s",
+				c.CertChainFile,
+				err.Error(),
+			)
+		}
+
+		for _, der := range cert.Certificate {
+			x509Cert, err := x509.ParseCertificate(der)
+
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Failed to parse the certificate %s: %s",
+					c.CertChainFile,
+					err.Error(),
+				)
+			}
+
+			if err := checkValidity(x509Cert, c.CertChainFile, now); err != nil {
+				return nil, err
+			}
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func loadCAFile(path string, now time.Time) (*x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the CA file %s: %s", path, err.Error())
+	}
+
+	var (
+		pool    = x509.NewCertPool()
+		found   = false
+		invalid error
+	)
+
+	for block, rest := pem.Decode(buf); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse the CA file %s: %s", path, err.Error())
+		}
+
+		// The bundles often contain expired roots, the other CAs can still
+		// verify the brokers
+		if err := checkValidity(cert, path, now); err != nil {
+			log.Warningf("Skipping a CA: %s", err.Error())
+			invalid = err
+			continue
+		}
+
+		pool.AddCert(cert)
+		found = true
+	}
+
+	switch {
+	case found:
+		return pool, nil
+	case invalid != nil:
+		return nil, fmt.Errorf("No valid CA found in %s: %s", path, invalid.Error())
+	}
+
+	return nil, fmt.Errorf("%s: %s", errInvalidCAFile.Error(), path)
+}
+
+// checkValidity rejects the certificates which are expired or not valid
+// yet, the ones expiring soon are only logged
+func checkValidity(cert *x509.Certificate, path string, now time.Time) error {
+	subject := cert.Subject.CommonName
+
+	switch {
+	case now.After(cert.NotAfter):
+		return fmt.Errorf(
+			"The certificate \"%s\" of %s expired on %s",
+			subject,
+			path,
+			cert.NotAfter.UTC().Format(time.RFC3339),
+		)
+	case now.Before(cert.NotBefore):
+		return fmt.Errorf(
+			"The certificate \"%s\" of %s is not valid before %s",
+			subject,
+			path,
+			cert.NotBefore.UTC().Format(time.RFC3339),
+		)
+	case cert.NotAfter.Sub(now) < expiryWarning:
+		log.Warningf(
+			"The certificate \"%s\" of %s expires on %s",
+			subject,
+			path,
+			cert.NotAfter.UTC().Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
+
+// BrokerConfig is a broker of the rabbitmq configuration block, the
+// sensu-go one with the TLS settings, TLS is disabled without ssl block
+type BrokerConfig struct {
+	*stdRabbitMQ.TransportConfig
+	SSL *SSLConfig `json:"ssl,omitempty"`
+}
+
+func (c *BrokerConfig) UnmarshalJSON(data []byte) error {
+	var (
+		config = stdRabbitMQ.TransportConfig{}
+		ssl    struct {
+			SSL *SSLConfig `json:"ssl"`
+		}
+	)
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &ssl); err != nil {
+		return err
+	}
+
+	c.TransportConfig, c.SSL = &config, ssl.SSL
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/upfluence/goutils/log"
+)
+
+const (
+	defaultHost    = "127.0.0.1"
+	defaultPort    = 6379
+	connectTimeout = 5 * time.Second
+	commandTimeout = 5 * time.Second
+)
+
+var errNotConnected = errors.New("The connection is not opened")
+
+// Config is the redis configuration block, like the ruby client the URL
+// takes precedence over the other settings
+type Config struct {
+	URL      string `json:"url,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Password string `json:"password,omitempty"`
+	DB       int    `json:"db,omitempty"`
+}
+
+func (c *Config) address() string {
+	host, port := defaultHost, defaultPort
+
+	if c.Host != "" {
+		host = c.Host
+	}
+
+	if c.Port != 0 {
+		port = c.Port
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// node identifies the server without leaking the password
+func (c *Config) node() string {
+	if c.URL == "" {
+		return c.address()
+	}
+
+	if i := strings.LastIndex(c.URL, "@"); i >= 0 {
+		return c.URL[i+1:]
+	}
+
+	return strings.TrimPrefix(c.URL, "redis://")
+}
+
+func (c *Config) dial(options ...redigo.DialOption) (redigo.Conn, error) {
+	options = append(options, redigo.DialConnectTimeout(connectTimeout))
+
+	if c.URL != "" {
+		return redigo.DialURL(c.URL, options...)
+	}
+
+	if c.Password != "" {
+		options = append(options, redigo.DialPassword(c.Password))
+	}
+
+	return redigo.Dial(
+		"tcp",
+		c.address(),
+		append(options, redigo.DialDatabase(c.DB))...,
+	)
+}
+
+// Transport implements the Sensu redis transport: the direct messages, such
+// as the keepalives and the results, are pushed to lists and the fanout
+// ones, such as the check requests, go through pub/sub channels
+type Transport struct {
+	Config *Config
+
+	mu          sync.Mutex
+	conn        redigo.Conn
+	closeChan   chan bool
+	closingChan chan bool
+}
+
+// NewTransport creates a Transport instance from a redis configuration
+func NewTransport(config *Config) *Transport {
+	return &Transport{Config: config, closingChan: make(chan bool, 1)}
+}
+
+// key follows the sensu-transport naming: transport:<type>:<name>
+func key(kind, name string) string {
+	return fmt.Sprintf("transport:%s:%s", kind, name)
+}
+
+func (t *Transport) Connect() error {
+	log.Noticef("Trying to connect to redis %s", t.Config.node())
+
+	conn, err := t.Config.dial(
+		redigo.DialReadTimeout(commandTimeout),
+		redigo.DialWriteTimeout(commandTimeout),
+	)
+
+	if err == nil {
+		_, err = conn.Do("PING")
+
+		if err != nil {
+			conn.Close()
+		}
+	}
+
+	if err != nil {
+		log.Errorf("Redis connection error: %s", err.Error())
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn != nil {
+		t.conn.Close()
+		close(t.closeChan)
+	}
+
+	t.conn = conn
+	t.closeChan = make(chan bool)
+
+	log.Noticef("Redis connection opened to %s", t.Config.node())
+
+	return nil
+}
+
+func (t *Transport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.conn != nil
+}
+
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Discard the disconnection noticed before the close
+	select {
+	case <-t.closingChan:
+	default:
+	}
+
+	if t.conn == nil {
+		return errNotConnected
+	}
+
+	close(t.closeChan)
+	err := t.conn.Close()
+	t.conn = nil
+
+	if err != nil {
+		return fmt.Errorf("Failed to close the connection: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (t *Transport) GetClosingChan() chan bool {
+	return t.closingChan
+}
+
+// disconnected notifies the client the connection is lost, once per
+// connection loss
+func (t *Transport) disconnected(err error) {
+	log.Warningf("Connection to redis %s lost: %s", t.Config.node(), err.Error())
+
+	select {
+	case t.closingChan <- true:
+	default:
+	}
+}
+
+func (t *Transport) Publish(exchangeType, exchangeName, _ string, message []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return errNotConnected
+	}
+
+	var err error
+
+	switch exchangeType {
+	case "fanout":
+		_, err = t.conn.Do("PUBLISH", key("channel", exchangeName), message)
+	case "direct":
+		_, err = t.conn.Do("RPUSH", key("list", exchangeName), message)
+	default:
+		return fmt.Errorf("Unsupported exchange type: %s", exchangeType)
+	}
+
+	if err != nil {
+		if _, ok := err.(redigo.Error); !ok {
+			t.disconnected(err)
+		}
+	}
+
+	return err
+}
+
+// Subscribe consumes the pub/sub channel of the exchange, the routing key
+// and the queue name are meaningless with redis
+func (t *Transport) Subscribe(
+	_,
+	exchangeName,
+	_ string,
+	messageChan chan []byte,
+	stopChan chan bool,
+) error {
+	t.mu.Lock()
+	closeChan := t.closeChan
+	connected := t.conn != nil
+	t.mu.Unlock()
+
+	if !connected {
+		return errNotConnected
+	}
+
+	conn, err := t.Config.dial()
+
+	if err != nil {
+		log.Errorf("Can't open the subscription connection: %s", err.Error())
+		return err
+	}
+
+	psc := redigo.PubSubConn{Conn: conn}
+	channel := key("channel", exchangeName)
+
+	if err := psc.Subscribe(channel); err != nil {
+		conn.Close()
+		log.Errorf("Can't subscribe to %s: %s", channel, err.Error())
+		return err
+	}
+
+	log.Noticef("Subscribed to %s", channel)
+
+	var (
+		messages = make(chan []byte)
+		errs     = make(chan error, 1)
+		done     = make(chan bool)
+	)
+
+	defer func() {
+		close(done)
+		psc.Close()
+	}()
+
+	go func() {
+		for {
+			switch v := psc.Receive().(type) {
+			case redigo.Message:
+				select {
+				case messages <- v.Data:
+				case <-done:
+					return
+				}
+			case error:
+				errs <- v
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case message := <-messages:
+			select {
+			case messageChan <- message:
+			case <-stopChan:
+				return nil
+			case <-closeChan:
+				return nil
+			}
+		case err := <-errs:
+			t.disconnected(err)
+			return err
+		case <-stopChan:
+			return nil
+		case <-closeChan:
+			return nil
+		}
+	}
+}
+
+// Info describes the transport state for the client info endpoint
+func (t *Transport) Info() map[string]interface{} {
+	return map[string]interface{}{"node": t.Config.node()}
+}
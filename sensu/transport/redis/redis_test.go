@@ -0,0 +1,318 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-process server implementing the subset of the redis
+// protocol used by the transport
+type fakeServer struct {
+	listener    net.Listener
+	password    string
+	mu          sync.Mutex
+	conns       []net.Conn
+	lists       map[string][]string
+	subscribers map[string][]net.Conn
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	s := &fakeServer{
+		listener:    l,
+		password:    password,
+		lists:       make(map[string][]string),
+		subscribers: make(map[string][]net.Conn),
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) config() *Config {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	return &Config{Host: host, Port: p, Password: s.password}
+}
+
+// disconnect closes the client connections, like a server restart would do
+func (s *fakeServer) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeServer) close() {
+	s.listener.Close()
+	s.disconnect()
+}
+
+func (s *fakeServer) list(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lists[name]
+}
+
+func (s *fakeServer) subscribed(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.subscribers[channel]) > 0
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		var size int
+
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func bulkStrings(xs ...string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(xs))
+
+	for _, x := range xs {
+		reply += fmt.Sprintf("$%d\r\n%s\r\n", len(x), x)
+	}
+
+	return reply
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(r)
+
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		io.WriteString(conn, s.handle(conn, args))
+		s.mu.Unlock()
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn, args []string) string {
+	switch args[0] {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH":
+		if args[1] != s.password {
+			return "-ERR invalid password\r\n"
+		}
+
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "RPUSH":
+		s.lists[args[1]] = append(s.lists[args[1]], args[2])
+
+		return fmt.Sprintf(":%d\r\n", len(s.lists[args[1]]))
+	case "PUBLISH":
+		for _, subscriber := range s.subscribers[args[1]] {
+			io.WriteString(subscriber, bulkStrings("message", args[1], args[2]))
+		}
+
+		return fmt.Sprintf(":%d\r\n", len(s.subscribers[args[1]]))
+	case "SUBSCRIBE":
+		s.subscribers[args[1]] = append(s.subscribers[args[1]], conn)
+
+		return "*3\r\n$9\r\nsubscribe\r\n" +
+			fmt.Sprintf("$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+	}
+
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func TestConnectWrongPassword(t *testing.T) {
+	server := newFakeServer(t, "secret")
+	defer server.close()
+
+	config := server.config()
+	config.Password = "wrong"
+
+	if err := NewTransport(config).Connect(); err == nil {
+		t.Error("Expected an error but got nil instead!")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	server := newFakeServer(t, "secret")
+	defer server.close()
+
+	transport := NewTransport(server.config())
+
+	if err := transport.Publish("direct", "results", "", []byte("foo")); err != errNotConnected {
+		t.Errorf("Expected error to be \"%s\" but got \"%v\" instead!", errNotConnected, err)
+	}
+
+	if err := transport.Connect(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer transport.Close()
+
+	for _, message := range []string{"foo", "bar"} {
+		if err := transport.Publish("direct", "keepalives", "", []byte(message)); err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+	}
+
+	if list := server.list("transport:list:keepalives"); !reflect.DeepEqual(list, []string{"foo", "bar"}) {
+		t.Errorf("Expected the keepalives list to be [foo bar] but got %v instead!", list)
+	}
+
+	if err := transport.Publish("topic", "results", "", []byte("foo")); err == nil {
+		t.Error("Expected an error for an unsupported exchange type but got nil instead!")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	server := newFakeServer(t, "")
+	defer server.close()
+
+	transport := NewTransport(server.config())
+
+	if err := transport.Connect(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	defer transport.Close()
+
+	messageChan, stopChan, done := make(chan []byte), make(chan bool), make(chan error)
+
+	go func() {
+		done <- transport.Subscribe("", "email", "foo-email", messageChan, stopChan)
+	}()
+
+	for i := 0; !server.subscribed("transport:channel:email") && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := transport.Publish("fanout", "email", "", []byte("foo")); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	select {
+	case message := <-messageChan:
+		if string(message) != "foo" {
+			t.Errorf("Expected message to be \"foo\" but got \"%s\" instead!", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a message but got nothing!")
+	}
+
+	stopChan <- true
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected a nil error but got \"%s\" instead!", err)
+	}
+}
+
+func TestSubscribeDisconnection(t *testing.T) {
+	server := newFakeServer(t, "")
+	defer server.close()
+
+	transport := NewTransport(server.config())
+
+	if err := transport.Connect(); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		done <- transport.Subscribe("", "email", "", make(chan []byte), make(chan bool))
+	}()
+
+	for i := 0; !server.subscribed("transport:channel:email") && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	server.disconnect()
+
+	select {
+	case <-transport.GetClosingChan():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the disconnection to be notified!")
+	}
+
+	if err := <-done; err == nil {
+		t.Error("Expected an error but got nil instead!")
+	}
+
+	transport.Close()
+
+	if transport.IsConnected() {
+		t.Error("Expected the transport to be disconnected!")
+	}
+}
+
+func TestConfigNode(t *testing.T) {
+	for _, tCase := range []struct {
+		config   Config
+		expected string
+	}{
+		{Config{}, "127.0.0.1:6379"},
+		{Config{Host: "redis", Port: 6380, Password: "secret"}, "redis:6380"},
+		{Config{URL: "redis://:secret@redis:6379/1"}, "redis:6379/1"},
+	} {
+		if node := tCase.config.node(); node != tCase.expected {
+			t.Errorf("Expected node to be %s but got %s instead!", tCase.expected, node)
+		}
+	}
+}
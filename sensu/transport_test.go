@@ -0,0 +1,187 @@
+package sensu
+
+import (
+	"os"
+	"testing"
+
+	"github.com/upfluence/goutils/testing/utils"
+	"github.com/upfluence/sensu-client-go/sensu/transport/api"
+	"github.com/upfluence/sensu-client-go/sensu/transport/memory"
+	"github.com/upfluence/sensu-client-go/sensu/transport/rabbitmq"
+	"github.com/upfluence/sensu-client-go/sensu/transport/redis"
+	"github.com/upfluence/sensu-go/sensu/transport"
+)
+
+func TestNewTransportDefault(t *testing.T) {
+	cfg, err := NewConfigFromFile(nil, "testdata/client-attributes.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(cfg.TransportName(), "rabbitmq", "transport name", t)
+
+	tr, err := NewTransport(cfg)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	rabbitMQTransport, ok := tr.(*rabbitmq.Transport)
+
+	if !ok {
+		t.Fatalf("Expected a RabbitMQ transport but got %T instead!", tr)
+	}
+
+	if len(rabbitMQTransport.Configs) != 1 {
+		t.Fatalf(
+			"Expected the config count to be 1 but got %d instead!",
+			len(rabbitMQTransport.Configs),
+		)
+	}
+
+	utils.ValidateStringParameter(
+		rabbitMQTransport.Configs[0].Host,
+		"10.0.0.6",
+		"host",
+		t,
+	)
+}
+
+func TestNewTransportFromEnvVar(t *testing.T) {
+	os.Setenv("SENSU_TRANSPORT_NAME", "memory")
+	defer os.Unsetenv("SENSU_TRANSPORT_NAME")
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-noSubs.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	tr, err := NewTransport(cfg)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if _, ok := tr.(*memory.Transport); !ok {
+		t.Errorf("Expected a memory transport but got %T instead!", tr)
+	}
+}
+
+func TestNewRedisTransport(t *testing.T) {
+	cfg, err := NewConfigFromFile(nil, "testdata/client-redis.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	tr, err := NewTransport(cfg)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	redisTransport, ok := tr.(*redis.Transport)
+
+	if !ok {
+		t.Fatalf("Expected a redis transport but got %T instead!", tr)
+	}
+
+	if *redisTransport.Config != (redis.Config{Host: "10.0.0.9", Port: 6380, DB: 2}) {
+		t.Errorf(
+			"Expected the settings of the redis block but got %+v instead!",
+			redisTransport.Config,
+		)
+	}
+
+	os.Setenv("REDIS_URL", "redis://10.0.0.10:6379/0")
+	defer os.Unsetenv("REDIS_URL")
+
+	if tr, err = NewTransport(cfg); err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	utils.ValidateStringParameter(
+		tr.(*redis.Transport).Config.URL,
+		"redis://10.0.0.10:6379/0",
+		"redis URL",
+		t,
+	)
+}
+
+func TestNewAPITransport(t *testing.T) {
+	for k, v := range map[string]string{
+		"SENSU_TRANSPORT_NAME": "http",
+		"SENSU_API_URL":        "https://sensu.example.com",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err := NewConfigFromFile(nil, "testdata/client-noSubs.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	tr, err := NewTransport(cfg)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	apiTransport, ok := tr.(*api.Transport)
+
+	if !ok {
+		t.Fatalf("Expected an API transport but got %T instead!", tr)
+	}
+
+	utils.ValidateStringParameter(
+		apiTransport.Config.URL,
+		"https://sensu.example.com",
+		"API URL",
+		t,
+	)
+}
+
+func TestNewTransportCustom(t *testing.T) {
+	cfg, err := NewConfigFromFile(nil, "testdata/client-transport.json")
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if _, err := NewTransport(cfg); err == nil {
+		t.Error("Expected an error for an unknown transport but got nil instead!")
+	}
+
+	var endpoint string
+
+	TransportStore["custom"] = func(cfg *Config) (transport.Transport, error) {
+		var settings struct {
+			Endpoint string `json:"endpoint"`
+		}
+
+		if err := cfg.Settings("custom", &settings); err != nil {
+			return nil, err
+		}
+
+		endpoint = settings.Endpoint
+
+		return memory.NewTransport(), nil
+	}
+	defer delete(TransportStore, "custom")
+
+	tr, err := NewTransport(cfg)
+
+	if err != nil {
+		t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+	}
+
+	if _, ok := tr.(*memory.Transport); !ok {
+		t.Errorf("Expected the custom transport but got %T instead!", tr)
+	}
+
+	utils.ValidateStringParameter(endpoint, "localhost:4242", "endpoint", t)
+}
@@ -0,0 +1,46 @@
+package sensu
+
+import "fmt"
+
+// protocolVersion is the version of the client protocol, it is part of the
+// subscription queue names and must remain stable across builds
+const protocolVersion = "1.2.0"
+
+// Build metadata, injected at build time through the linker:
+// go build -ldflags "-X github.com/upfluence/sensu-client-go/sensu.Version=1.3.0"
+var (
+	Version   = "dev"
+	GitCommit = ""
+	BuildDate = ""
+)
+
+type buildInfo struct {
+	Commit string `json:"commit,omitempty"`
+	Date   string `json:"date,omitempty"`
+}
+
+// build returns the build metadata sent along with the keepalives, nil when
+// it was not injected
+func build() *buildInfo {
+	if GitCommit == "" && BuildDate == "" {
+		return nil
+	}
+
+	return &buildInfo{GitCommit, BuildDate}
+}
+
+// VersionString describes the running build, as printed by the version
+// subcommand
+func VersionString() string {
+	s := fmt.Sprintf("sensu-client-go %s (protocol %s)", Version, protocolVersion)
+
+	if GitCommit != "" {
+		s += fmt.Sprintf(", commit %s", GitCommit)
+	}
+
+	if BuildDate != "" {
+		s += fmt.Sprintf(", built on %s", BuildDate)
+	}
+
+	return s
+}
@@ -0,0 +1,68 @@
+package sensu
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/upfluence/goutils/testing/utils"
+	stdClient "github.com/upfluence/sensu-go/sensu/client"
+)
+
+func withBuildInfo(version, commit, date string, fn func()) {
+	origVersion, origCommit, origDate := Version, GitCommit, BuildDate
+	Version, GitCommit, BuildDate = version, commit, date
+	defer func() { Version, GitCommit, BuildDate = origVersion, origCommit, origDate }()
+
+	fn()
+}
+
+func TestVersionString(t *testing.T) {
+	withBuildInfo("1.3.0", "", "", func() {
+		utils.ValidateStringParameter(
+			VersionString(),
+			"sensu-client-go 1.3.0 (protocol "+protocolVersion+")",
+			"version",
+			t,
+		)
+	})
+
+	withBuildInfo("1.3.0", "abc123", "2017-08-01T10:00:00Z", func() {
+		utils.ValidateStringParameter(
+			VersionString(),
+			"sensu-client-go 1.3.0 (protocol "+protocolVersion+"), commit abc123, built on 2017-08-01T10:00:00Z",
+			"version",
+			t,
+		)
+	})
+}
+
+func TestKeepAliveBuildInfo(t *testing.T) {
+	withBuildInfo("1.3.0", "abc123", "2017-08-01T10:00:00Z", func() {
+		transport := newTestTransport()
+		cfg := &Config{
+			config: &configPayload{
+				Client: &clientConfig{Client: &stdClient.Client{Name: "Test"}},
+			},
+		}
+
+		NewKeepAlive(&Client{Transport: transport, Config: cfg}).publishKeepAlive()
+
+		var payload struct {
+			Version string    `json:"version"`
+			Build   buildInfo `json:"build"`
+		}
+
+		if err := json.Unmarshal(lastMessage(transport).Body, &payload); err != nil {
+			t.Fatalf("Expected a nil error but got \"%s\" instead!", err)
+		}
+
+		utils.ValidateStringParameter(payload.Version, "1.3.0", "version", t)
+		utils.ValidateStringParameter(payload.Build.Commit, "abc123", "commit", t)
+		utils.ValidateStringParameter(
+			payload.Build.Date,
+			"2017-08-01T10:00:00Z",
+			"build date",
+			t,
+		)
+	})
+}